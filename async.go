@@ -0,0 +1,257 @@
+package elog
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LOG_ASYNC_QUEUE_SIZE = 10000
+	LOG_ASYNC_BATCH      = 256
+	// LOG_ASYNC_STARVATION is the number of WARN and above records written
+	// in a row before a waiting lower record gets its turn.
+	LOG_ASYNC_STARVATION = 8
+)
+
+type asyncRecord struct {
+	r, stack *Record
+}
+
+// asyncQueue holds the records of an async logger until its writer
+// goroutine writes them: WARN and above first, so the important records of
+// a burst reach the handler before the DEBUG and INFO backlog.
+type asyncQueue struct {
+	mutex   sync.Mutex
+	high    []asyncRecord
+	low     []asyncRecord
+	size    int
+	streak  int
+	dropped int64
+	inline  int64
+	wake    chan struct{}
+
+	// latency budget mode, guarded by the logger mutex
+	budget   time.Duration
+	queuing  int32
+	lastFast bool
+	switches int64
+	queued   int64 // records queued since the last switch
+}
+
+// QueueStats reports the queue of an async logger: Pending records wait to
+// be written, Dropped DEBUG and INFO records were discarded for a full
+// queue and Inline WARN and above ones were written by the caller instead.
+// With a latency budget Queuing tells whether the logger queues at the
+// moment and Switches how often it went from writing to queuing.
+type QueueStats struct {
+	Pending  int
+	Dropped  int64
+	Inline   int64
+	Queuing  bool
+	Switches int64
+}
+
+// WithAsync makes log calls return once their record is queued; a
+// goroutine writes the queue, up to size records (LOG_ASYNC_QUEUE_SIZE when
+// 0). Under backlog WARN and above records are written first, with one
+// lower record every LOG_ASYNC_STARVATION so those are never stuck. A full
+// queue drops DEBUG and INFO records, the oldest first when a higher one
+// arrives, and has callers write WARN and above themselves. Flush and
+// Shutdown write the queue first.
+func WithAsync(size int) Option {
+	return func(el *EasyLogger) {
+		if size <= 0 {
+			size = LOG_ASYNC_QUEUE_SIZE
+		}
+		if el.async != nil {
+			el.async.mutex.Lock()
+			el.async.size = size
+			el.async.mutex.Unlock()
+			return
+		}
+		el.async = &asyncQueue{size: size, wake: make(chan struct{}, 1), queuing: 1}
+		go el.async.run(el)
+	}
+}
+
+// WithLatencyBudget writes records synchronously as long as a write takes
+// no longer than budget, e.g. 200µs. A slower write switches the logger to
+// the async queue of WithAsync, added with the default size if missing,
+// until the queue is written out with writes back under budget. A WARN
+// record notes each switch. A budget <= 0 turns the mode off, leaving the
+// plain WithAsync queue if there is one.
+func WithLatencyBudget(budget time.Duration) Option {
+	return func(el *EasyLogger) {
+		if budget <= 0 {
+			if el.async != nil {
+				el.async.budget = 0
+				atomic.StoreInt32(&el.async.queuing, 1)
+			}
+			return
+		}
+		if el.async == nil {
+			WithAsync(0)(el)
+		}
+		el.async.budget = budget
+		atomic.StoreInt32(&el.async.queuing, 0)
+	}
+}
+
+// write writes r and its stack record while writes keep to the latency
+// budget and queues them otherwise. It is called with the mutex held.
+func (aq *asyncQueue) write(el *EasyLogger, r, stack *Record) {
+	if atomic.LoadInt32(&aq.queuing) != 0 {
+		aq.queued++
+		aq.push(el, r, stack)
+		return
+	}
+	start := time.Now()
+	el.writeQueued(asyncRecord{r, stack})
+	if elapsed := time.Since(start); elapsed > aq.budget {
+		atomic.StoreInt32(&aq.queuing, 1)
+		atomic.AddInt64(&aq.switches, 1)
+		aq.queued = 0
+		aq.push(el, &Record{
+			Level:   LOG_LEVEL_WARN,
+			Time:    el.clock.Now(),
+			Message: "elog: write took " + elapsed.String() + ", over the " + aq.budget.String() + " budget, queuing records",
+		}, nil)
+	}
+}
+
+// settle switches back to synchronous writes once the queue is written out
+// and its last write kept to the budget. It is called with the mutex held.
+func (aq *asyncQueue) settle(el *EasyLogger) {
+	if aq.budget <= 0 || atomic.LoadInt32(&aq.queuing) == 0 || !aq.lastFast {
+		return
+	}
+	atomic.StoreInt32(&aq.queuing, 0)
+	el.writeRecord(&Record{
+		Level:   LOG_LEVEL_WARN,
+		Time:    el.clock.Now(),
+		Message: "elog: handler back under the " + aq.budget.String() + " budget after " + strconv.FormatInt(aq.queued, 10) + " queued records, writing synchronously",
+	})
+}
+
+// push queues r and its stack record, or writes them itself when the queue
+// is full of WARN and above records. It is called with the mutex held.
+func (aq *asyncQueue) push(el *EasyLogger, r, stack *Record) {
+	item := asyncRecord{r, stack}
+	high := r.Level >= LOG_LEVEL_WARN
+	aq.mutex.Lock()
+	if len(aq.high)+len(aq.low) >= aq.size {
+		switch {
+		case high && len(aq.low) > 0:
+			aq.low = aq.low[1:]
+			aq.dropped++
+		case high:
+			aq.inline++
+			aq.mutex.Unlock()
+			el.writeQueued(item)
+			return
+		default:
+			aq.dropped++
+			aq.mutex.Unlock()
+			return
+		}
+	}
+	if high {
+		aq.high = append(aq.high, item)
+	} else {
+		aq.low = append(aq.low, item)
+	}
+	aq.mutex.Unlock()
+	select {
+	case aq.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pop returns the next record to write: the oldest WARN and above one,
+// unless LOG_ASYNC_STARVATION of them went before a waiting lower one.
+func (aq *asyncQueue) pop() (asyncRecord, bool) {
+	aq.mutex.Lock()
+	defer aq.mutex.Unlock()
+	var item asyncRecord
+	switch {
+	case len(aq.high) > 0 && (len(aq.low) == 0 || aq.streak < LOG_ASYNC_STARVATION):
+		item, aq.high = aq.high[0], aq.high[1:]
+		aq.streak++
+	case len(aq.low) > 0:
+		item, aq.low = aq.low[0], aq.low[1:]
+		aq.streak = 0
+	default:
+		aq.streak = 0
+		return item, false
+	}
+	// let the emptied slices go instead of creeping through the array
+	if len(aq.high) == 0 {
+		aq.high = nil
+	}
+	if len(aq.low) == 0 {
+		aq.low = nil
+	}
+	return item, true
+}
+
+func (aq *asyncQueue) pending() int {
+	aq.mutex.Lock()
+	defer aq.mutex.Unlock()
+	return len(aq.high) + len(aq.low)
+}
+
+// drain writes up to max queued records, all of them when max is 0, and
+// reports whether the queue still holds some. It is called with the mutex
+// held.
+func (aq *asyncQueue) drain(el *EasyLogger, max int) bool {
+	for n := 0; max == 0 || n < max; n++ {
+		item, ok := aq.pop()
+		if !ok {
+			aq.settle(el)
+			return false
+		}
+		start := time.Now()
+		el.writeQueued(item)
+		aq.lastFast = time.Since(start) <= aq.budget
+	}
+	return aq.pending() > 0
+}
+
+func (aq *asyncQueue) run(el *EasyLogger) {
+	for {
+		select {
+		case <-aq.wake:
+		case <-el.done:
+			return
+		}
+		for more := true; more; {
+			el.lock()
+			more = atomic.LoadInt32(&el.closed) == 0 && aq.drain(el, LOG_ASYNC_BATCH)
+			el.unlock()
+		}
+	}
+}
+
+func (el *EasyLogger) writeQueued(item asyncRecord) {
+	el.writeRecord(item.r)
+	if item.stack != nil {
+		el.writeRecord(item.stack)
+	}
+}
+
+// AsyncStats reports the queue of a logger configured WithAsync.
+func (el *EasyLogger) AsyncStats() QueueStats {
+	if el.async == nil {
+		return QueueStats{}
+	}
+	aq := el.async
+	aq.mutex.Lock()
+	defer aq.mutex.Unlock()
+	return QueueStats{Pending: len(aq.high) + len(aq.low), Dropped: aq.dropped, Inline: aq.inline, Queuing: atomic.LoadInt32(&aq.queuing) != 0, Switches: atomic.LoadInt64(&aq.switches)}
+}
+
+func AsyncStats() QueueStats {
+	return logger.AsyncStats()
+}
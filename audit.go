@@ -0,0 +1,185 @@
+package elog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LOG_CONFIG_HISTORY = 100
+
+	LOG_FIELD_SETTING = "setting"
+	LOG_FIELD_BEFORE  = "before"
+	LOG_FIELD_AFTER   = "after"
+	LOG_FIELD_SOURCE  = "source"
+)
+
+// The sources of a configuration change.
+const (
+	LOG_SOURCE_API       = "api"
+	LOG_SOURCE_FLAG      = "flag"
+	LOG_SOURCE_ENV       = "env"
+	LOG_SOURCE_GUARD     = "guard"
+	LOG_SOURCE_CONFIGURE = "configure"
+)
+
+// ConfigChange is one setting changed at runtime. Source tells how it was
+// changed, Caller where: the first caller outside elog.
+type ConfigChange struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Caller  string    `json:"caller"`
+	Setting string    `json:"setting"`
+	Before  string    `json:"before"`
+	After   string    `json:"after"`
+}
+
+// configAudit holds the last LOG_CONFIG_HISTORY changes and those made by
+// Configure, reported once it releases the logger mutex. Guarded by
+// configMutex.
+type configAudit struct {
+	history []ConfigChange
+	pending []ConfigChange
+}
+
+type configSetting struct {
+	name  string
+	value string
+}
+
+func (c *loggerConfig) settings() []configSetting {
+	var packages []string
+	if c.packages != nil {
+		for _, pl := range c.packages.levels {
+			packages = append(packages, pl.prefix+"="+getLogLevelString(pl.level))
+		}
+		sort.Strings(packages)
+	}
+	return []configSetting{
+		{"level", getLogLevelString(c.level)},
+		{"log_to_stderr", strconv.FormatBool(c.logToStderr)},
+		{"encoder", fmt.Sprintf("%T", c.encoder)},
+		{"format_check", strconv.FormatBool(c.formatCheck)},
+		{"sanitize", strconv.Itoa(c.sanitize)},
+		{"development", strconv.FormatBool(c.development)},
+		{"event_policy", strconv.Itoa(c.eventPolicy)},
+		{"package_levels", strings.Join(packages, ",")},
+		{"sample", strconv.Itoa(c.sample)},
+	}
+}
+
+func (el *EasyLogger) handlerSettings() []configSetting {
+	settings := []configSetting{{"handler", describeHandler(el.writer)}}
+	var routes []string
+	for _, rt := range el.routes {
+		routes = append(routes, rt.describe())
+	}
+	return append(settings, configSetting{"routes", strings.Join(routes, ",")})
+}
+
+func diffSettings(before, after []configSetting, source string) []ConfigChange {
+	var changes []ConfigChange
+	caller := externalCaller()
+	now := time.Now()
+	for i := range before {
+		if before[i].value != after[i].value {
+			changes = append(changes, ConfigChange{Time: now, Source: source, Caller: caller,
+				Setting: before[i].name, Before: before[i].value, After: after[i].value})
+		}
+	}
+	return changes
+}
+
+// externalCaller returns file:line of the first caller outside elog and
+// the flag package.
+func externalCaller() string {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, elogPackage+".") && !strings.HasPrefix(frame.Function, "flag.") {
+			return shortFileName(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// audit records changes with configMutex held and returns those to report
+// now; changes made inside Configure are reported when it returns.
+func (el *EasyLogger) audit(changes []ConfigChange) []ConfigChange {
+	if len(changes) == 0 || atomic.LoadInt32(&el.audited) == 0 {
+		return nil
+	}
+	if atomic.LoadInt32(&el.configuring) != 0 {
+		el.configAudit.pending = append(el.configAudit.pending, changes...)
+		return nil
+	}
+	el.configAudit.history = append(el.configAudit.history, changes...)
+	if n := len(el.configAudit.history) - LOG_CONFIG_HISTORY; n > 0 {
+		el.configAudit.history = append(el.configAudit.history[:0], el.configAudit.history[n:]...)
+	}
+	return changes
+}
+
+// reportConfigChanges logs every change to the self log at INFO, with the
+// setting, before, after and source fields.
+func reportConfigChanges(changes []ConfigChange) {
+	for _, change := range changes {
+		r := &Record{Level: LOG_LEVEL_INFO, File: change.Caller, Message: "configuration changed",
+			Fields: Fields{LOG_FIELD_SETTING: change.Setting, LOG_FIELD_BEFORE: change.Before,
+				LOG_FIELD_AFTER: change.After, LOG_FIELD_SOURCE: change.Source}}
+		if colon := strings.LastIndexByte(change.Caller, ':'); colon >= 0 {
+			r.File = change.Caller[:colon]
+			r.Line, _ = strconv.Atoi(change.Caller[colon+1:])
+		}
+		selfLogRecord(r)
+	}
+}
+
+// ConfigHistory returns the last LOG_CONFIG_HISTORY configuration changes
+// made at runtime, oldest first.
+func (el *EasyLogger) ConfigHistory() []ConfigChange {
+	el.configMutex.Lock()
+	defer el.configMutex.Unlock()
+	return append([]ConfigChange(nil), el.configAudit.history...)
+}
+
+func ConfigHistory() []ConfigChange {
+	return logger.ConfigHistory()
+}
+
+// ConfigHistoryHandler serves the configuration history as JSON with
+// ?format=json and as text otherwise.
+func (el *EasyLogger) ConfigHistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		history := el.ConfigHistory()
+		if req.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(history)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeConfigHistory(w, history)
+	})
+}
+
+func ConfigHistoryHandler() http.Handler {
+	return logger.ConfigHistoryHandler()
+}
+
+func writeConfigHistory(w io.Writer, history []ConfigChange) {
+	for _, change := range history {
+		fmt.Fprintf(w, "%s %-9s %-16s %q -> %q by %s\n", formatTime(change.Time), change.Source,
+			change.Setting, change.Before, change.After, change.Caller)
+	}
+}
@@ -0,0 +1,213 @@
+package elog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BridgeKeys names the keys of the records a bridged library writes.
+type BridgeKeys struct {
+	Level   string
+	Message string
+	Time    string
+	Caller  string // "file:line"
+}
+
+var (
+	LogrusKeys  = BridgeKeys{Level: "level", Message: "msg", Time: "time", Caller: "file"}
+	ZerologKeys = BridgeKeys{Level: "level", Message: "message", Time: "time", Caller: "caller"}
+)
+
+// EasyBridgeWriter is an io.Writer for the output of another logging
+// library: every JSON or logfmt (key=value) line it receives becomes an
+// elog record with the original level, time, caller and fields, written to
+// the handlers of the logger. The typed adapters, a logrus.Hook and a
+// zerolog.LevelWriter, live in the logrushook and zerologwriter modules so
+// that elog itself does not depend on those libraries.
+type EasyBridgeWriter struct {
+	el      *EasyLogger
+	keys    BridgeKeys
+	mutex   sync.Mutex
+	partial []byte
+}
+
+func NewEasyBridgeWriter(el *EasyLogger, keys BridgeKeys) *EasyBridgeWriter {
+	return &EasyBridgeWriter{el: el, keys: keys}
+}
+
+// NewLogrusWriter bridges logrus: logrus.SetOutput(elog.NewLogrusWriter(log)),
+// with the JSONFormatter or the TextFormatter.
+func NewLogrusWriter(el *EasyLogger) *EasyBridgeWriter {
+	return NewEasyBridgeWriter(el, LogrusKeys)
+}
+
+// NewZerologWriter bridges zerolog: zerolog.New(elog.NewZerologWriter(log)).
+func NewZerologWriter(el *EasyLogger) *EasyBridgeWriter {
+	return NewEasyBridgeWriter(el, ZerologKeys)
+}
+
+func (ebw *EasyBridgeWriter) Write(p []byte) (int, error) {
+	return ebw.WriteLevel(0, p)
+}
+
+// WriteLevel writes p like Write, its records at level whatever their
+// level key says, unless level is 0. It serves writers that are told the
+// level, such as zerolog.LevelWriter.
+func (ebw *EasyBridgeWriter) WriteLevel(level int, p []byte) (int, error) {
+	ebw.mutex.Lock()
+	defer ebw.mutex.Unlock()
+	data := append(ebw.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		ebw.writeLine(bytes.TrimSpace(data[:i]), level)
+		data = data[i+1:]
+	}
+	ebw.partial = append(ebw.partial[:0], data...)
+	return len(p), nil
+}
+
+func (ebw *EasyBridgeWriter) writeLine(line []byte, level int) {
+	if len(line) == 0 {
+		return
+	}
+	var values map[string]interface{}
+	if line[0] == '{' {
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		if decoder.Decode(&values) != nil {
+			values = nil
+		}
+	}
+	if values == nil {
+		values = parseLogfmt(string(line))
+	}
+	r := &Record{Level: LOG_LEVEL_INFO, Message: ""}
+	for k, v := range values {
+		switch k {
+		case ebw.keys.Level:
+			r.Level = bridgeLevel(toString(v))
+		case ebw.keys.Message:
+			r.Message = toString(v)
+		case ebw.keys.Time:
+			r.Time = bridgeTime(v)
+		case ebw.keys.Caller:
+			caller := toString(v)
+			if colon := strings.LastIndexByte(caller, ':'); colon >= 0 {
+				if n, err := strconv.Atoi(caller[colon+1:]); err == nil {
+					r.File = shortFileName(caller[:colon])
+					r.Line = n
+					continue
+				}
+			}
+			r.File = shortFileName(caller)
+		default:
+			if r.Fields == nil {
+				r.Fields = Fields{}
+			}
+			r.Fields[k] = v
+		}
+	}
+	if len(values) == 0 {
+		r.Message = string(line)
+	}
+	if r.File == "" {
+		r.File, r.Line = "???", 1
+	}
+	if level != 0 {
+		r.Level = level
+	}
+	ebw.el.Emit(r)
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return formatFieldValue(v)
+}
+
+func bridgeLevel(level string) int {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return LOG_LEVEL_DEBUG
+	case "warn", "warning":
+		return LOG_LEVEL_WARN
+	case "error":
+		return LOG_LEVEL_ERROR
+	case "fatal", "panic":
+		return LOG_LEVEL_FATAL
+	}
+	return LOG_LEVEL_INFO
+}
+
+// bridgeTime accepts RFC 3339 strings and unix times in seconds or
+// milliseconds; anything else is replaced by the current time.
+func bridgeTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return parsed
+		}
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			if n > 1e12 {
+				return time.Unix(0, n*int64(time.Millisecond))
+			}
+			return time.Unix(n, 0)
+		}
+	}
+	return time.Time{}
+}
+
+// parseLogfmt parses key=value pairs, values optionally double quoted Go
+// style, as written by the logrus TextFormatter.
+func parseLogfmt(line string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for len(line) > 0 {
+		line = strings.TrimLeft(line, " ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			break
+		}
+		key := line[:eq]
+		if strings.ContainsAny(key, " \"") {
+			break
+		}
+		line = line[eq+1:]
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			end := 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				break
+			}
+			unquoted, err := strconv.Unquote(line[:end+1])
+			if err != nil {
+				unquoted = line[1:end]
+			}
+			value = unquoted
+			line = line[end+1:]
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			value = line[:end]
+			line = line[end:]
+		}
+		values[key] = value
+	}
+	return values
+}
@@ -0,0 +1,85 @@
+package elog
+
+import (
+	"strconv"
+	"time"
+)
+
+type callSiteBudget struct {
+	maxBytes int64
+	window   time.Duration
+	sites    map[string]*siteBudget
+}
+
+type siteBudget struct {
+	since           time.Time
+	bytes           int64
+	suppressed      int64
+	suppressedBytes int64
+	last            Record
+}
+
+// WithCallSiteBudget limits every call site (file:line) to maxBytes of
+// encoded output per window. Records over the budget are dropped; when the
+// window ends a WARN summary reports how many records and bytes the site
+// lost, so one runaway debug line cannot fill the disk.
+func WithCallSiteBudget(maxBytes int64, window time.Duration) Option {
+	return func(el *EasyLogger) {
+		el.budget = &callSiteBudget{maxBytes: maxBytes, window: window, sites: map[string]*siteBudget{}}
+	}
+}
+
+func (cb *callSiteBudget) allow(el *EasyLogger, r *Record, n int64) bool {
+	now := el.clock.Now()
+	site := r.File + ":" + strconv.Itoa(r.Line)
+	sb, ok := cb.sites[site]
+	if !ok {
+		sb = &siteBudget{since: now}
+		cb.sites[site] = sb
+	} else if now.Sub(sb.since) >= cb.window {
+		cb.summarize(el, site, sb)
+		sb.since = now
+		sb.bytes = 0
+	}
+	if sb.bytes+n > cb.maxBytes {
+		sb.suppressed++
+		sb.suppressedBytes += n
+		sb.last = *r
+		return false
+	}
+	sb.bytes += n
+	return true
+}
+
+func (cb *callSiteBudget) tick(el *EasyLogger) {
+	now := el.clock.Now()
+	for site, sb := range cb.sites {
+		if now.Sub(sb.since) < cb.window {
+			continue
+		}
+		cb.summarize(el, site, sb)
+		delete(cb.sites, site)
+	}
+}
+
+func (cb *callSiteBudget) summarize(el *EasyLogger, site string, sb *siteBudget) {
+	if sb.suppressed == 0 {
+		return
+	}
+	summary := &Record{
+		Level: LOG_LEVEL_WARN,
+		Name:  sb.last.Name,
+		Time:  el.clock.Now(),
+		File:  sb.last.File,
+		Line:  sb.last.Line,
+		Message: "elog: suppressed " + strconv.FormatInt(sb.suppressed, 10) + " records (" +
+			strconv.FormatInt(sb.suppressedBytes, 10) + " bytes) from " + site + " over budget of " +
+			strconv.FormatInt(cb.maxBytes, 10) + " bytes per " + cb.window.String(),
+	}
+	sb.suppressed = 0
+	sb.suppressedBytes = 0
+	budget := el.budget
+	el.budget = nil
+	el.writeRecord(summary)
+	el.budget = budget
+}
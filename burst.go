@@ -0,0 +1,73 @@
+package elog
+
+import (
+	"strconv"
+	"time"
+)
+
+type burstState struct {
+	threshold int64
+	factor    int
+	count     int64 // records since the last tick
+	last      time.Time
+	active    bool
+	since     time.Time
+	records   int64 // records during the burst
+	ticks     int
+}
+
+// WithBurstMode coalesces writes during log storms: when more than
+// threshold records per second arrive between two flush ticks, the handler
+// is flushed only every factor ticks until the rate falls under half the
+// threshold, then an INFO note reports the burst.
+func WithBurstMode(threshold int, factor int) Option {
+	return func(el *EasyLogger) {
+		if factor < 2 {
+			factor = 2
+		}
+		el.burst = &burstState{threshold: int64(threshold), factor: factor, last: el.clock.Now()}
+	}
+}
+
+func (bs *burstState) add() {
+	bs.count++
+	if bs.active {
+		bs.records++
+	}
+}
+
+// tick updates the burst state and reports whether the handler should be
+// flushed on this tick.
+func (bs *burstState) tick(el *EasyLogger) bool {
+	now := el.clock.Now()
+	elapsed := now.Sub(bs.last)
+	bs.last = now
+	count := bs.count
+	bs.count = 0
+	if elapsed <= 0 {
+		return !bs.active
+	}
+	rate := count * int64(time.Second) / int64(elapsed)
+	if !bs.active {
+		if rate > bs.threshold {
+			bs.active = true
+			bs.since = now
+			bs.records = count
+			bs.ticks = 0
+		}
+		return !bs.active
+	}
+	if rate*2 < bs.threshold {
+		bs.active = false
+		el.writeRecord(&Record{
+			Level: LOG_LEVEL_INFO,
+			Time:  now,
+			Message: "elog: burst mode ended after " + now.Sub(bs.since).String() + ", " +
+				strconv.FormatInt(bs.records, 10) + " records written with flushes every " +
+				strconv.Itoa(bs.factor) + " ticks",
+		})
+		return true
+	}
+	bs.ticks++
+	return bs.ticks%bs.factor == 0
+}
@@ -0,0 +1,120 @@
+package elog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LOG_MAX_CACHED_CALLERS = 4096
+)
+
+// stringCache maps keys to formatted strings. It is meant for the few
+// hundred call sites a program logs from; when it grows past its limit it
+// starts over rather than tracking recency.
+type stringCache struct {
+	mutex sync.RWMutex
+	limit int
+	m     map[interface{}]string
+}
+
+func newStringCache(limit int) *stringCache {
+	return &stringCache{limit: limit, m: map[interface{}]string{}}
+}
+
+func (sc *stringCache) get(key interface{}, format func() string) string {
+	sc.mutex.RLock()
+	s, ok := sc.m[key]
+	sc.mutex.RUnlock()
+	if ok {
+		return s
+	}
+	s = format()
+	sc.mutex.Lock()
+	if len(sc.m) >= sc.limit {
+		sc.m = map[interface{}]string{}
+	}
+	sc.m[key] = s
+	sc.mutex.Unlock()
+	return s
+}
+
+var shortFileCache = newStringCache(LOG_MAX_CACHED_CALLERS)
+
+func shortFileName(file string) string {
+	return shortFileCache.get(file, func() string {
+		slash := strings.LastIndex(file, "/")
+		if slash >= 0 {
+			return file[slash+1:]
+		}
+		return file
+	})
+}
+
+var funcNameCache = newStringCache(LOG_MAX_CACHED_CALLERS)
+
+// callerFunction splits the function at pc into its package path and its
+// name within the package, e.g. "github.com/a/b" and "(*T).Run".
+func callerFunction(pc uintptr) (pkg string, function string) {
+	if pc == 0 {
+		return "", ""
+	}
+	name := funcNameCache.get(pc, func() string {
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			return fn.Name()
+		}
+		return ""
+	})
+	slash := strings.LastIndex(name, "/")
+	dot := strings.IndexByte(name[slash+1:], '.')
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+1+dot+1:]
+}
+
+type callerKey struct {
+	file string
+	line int
+}
+
+var callerTextCache = newStringCache(LOG_MAX_CACHED_CALLERS)
+
+// callerText returns "[file:f line:n] " for the text header.
+func callerText(file string, line int) string {
+	return callerTextCache.get(callerKey{file, line}, func() string {
+		return "[file:" + file + " line:" + strconv.Itoa(line) + "] "
+	})
+}
+
+// levelToken returns "[LEVEL]" for the text header.
+func levelToken(level int) string {
+	if token, ok := currentLevels().tokens[level]; ok {
+		return token
+	}
+	return "[" + getLogLevelString(level) + "]"
+}
+
+type cachedTime struct {
+	sec  int64
+	loc  *time.Location
+	text string
+}
+
+var lastTimeText atomic.Value
+
+// timeToken returns "[2006-01-02 15:04:05]", formatting at most once per
+// second in the common case of monotonically logged records.
+func timeToken(t time.Time) string {
+	sec := t.Unix()
+	if ct, ok := lastTimeText.Load().(*cachedTime); ok && ct.sec == sec && ct.loc == t.Location() {
+		return ct.text
+	}
+	text := "[" + formatTime(t) + "]"
+	lastTimeText.Store(&cachedTime{sec: sec, loc: t.Location(), text: text})
+	return text
+}
@@ -0,0 +1,110 @@
+package elog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// CallSiteStat is the log volume attributed to one call site.
+type CallSiteStat struct {
+	Site  string `json:"site"`
+	Count int64  `json:"count"`
+	Bytes int64  `json:"bytes"`
+}
+
+type callSiteProfile struct {
+	every int
+	seen  int
+	sites map[string]*CallSiteStat
+}
+
+// WithCallSiteProfile accounts the records and encoded bytes produced by
+// each call site (file:line). Only one record out of every sampleEvery is
+// measured and its cost is scaled up, keeping the overhead low on hot paths;
+// 1 measures every record.
+func WithCallSiteProfile(sampleEvery int) Option {
+	return func(el *EasyLogger) {
+		if sampleEvery < 1 {
+			sampleEvery = 1
+		}
+		el.callSites = &callSiteProfile{every: sampleEvery, sites: map[string]*CallSiteStat{}}
+	}
+}
+
+func (csp *callSiteProfile) sample() bool {
+	csp.seen++
+	if csp.seen < csp.every {
+		return false
+	}
+	csp.seen = 0
+	return true
+}
+
+func (csp *callSiteProfile) add(r *Record, n int64) {
+	site := r.File + ":" + strconv.Itoa(r.Line)
+	stat, ok := csp.sites[site]
+	if !ok {
+		stat = &CallSiteStat{Site: site}
+		csp.sites[site] = stat
+	}
+	stat.Count += int64(csp.every)
+	stat.Bytes += n * int64(csp.every)
+}
+
+// CallSiteReport returns the top call sites by bytes written, all of them if
+// top <= 0. It is empty unless WithCallSiteProfile is set.
+func (el *EasyLogger) CallSiteReport(top int) []CallSiteStat {
+	el.lock()
+	defer el.unlock()
+	if el.callSites == nil {
+		return nil
+	}
+	report := make([]CallSiteStat, 0, len(el.callSites.sites))
+	for _, stat := range el.callSites.sites {
+		report = append(report, *stat)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Bytes != report[j].Bytes {
+			return report[i].Bytes > report[j].Bytes
+		}
+		return report[i].Site < report[j].Site
+	})
+	if top > 0 && len(report) > top {
+		report = report[:top]
+	}
+	return report
+}
+
+func (el *EasyLogger) ResetCallSiteProfile() {
+	el.lock()
+	defer el.unlock()
+	if el.callSites != nil {
+		el.callSites.sites = map[string]*CallSiteStat{}
+	}
+}
+
+func CallSiteReport(top int) []CallSiteStat {
+	return logger.CallSiteReport(top)
+}
+
+// CallSiteHandler serves the call site report, as JSON with ?format=json
+// and as a text table otherwise; ?top=N limits the number of sites.
+func (el *EasyLogger) CallSiteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		top, _ := strconv.Atoi(req.URL.Query().Get("top"))
+		report := el.CallSiteReport(top)
+		if req.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%-40s %12s %14s\n", "SITE", "COUNT", "BYTES")
+		for _, stat := range report {
+			fmt.Fprintf(w, "%-40s %12d %14d\n", stat.Site, stat.Count, stat.Bytes)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package elog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os/exec"
+	"path/filepath"
+)
+
+// CaptureCommand logs every line the command writes to stdout at level and
+// every stderr line at WARN (or level when higher), with the fields cmd and
+// stream. It sets cmd.Stdout and cmd.Stderr, call it before cmd.Start or
+// cmd.Run; cmd.Wait returns after the last line is logged.
+func (el *EasyLogger) CaptureCommand(cmd *exec.Cmd, level int) {
+	file, line, pc := getCaller(el.depth - 1)
+	name := filepath.Base(cmd.Path)
+	errLevel := level
+	if errLevel < LOG_LEVEL_WARN {
+		errLevel = LOG_LEVEL_WARN
+	}
+	cmd.Stdout = &captureWriter{el: el, level: level, file: file, line: line, pc: pc, fields: Fields{"cmd": name, "stream": "stdout"}}
+	cmd.Stderr = &captureWriter{el: el, level: errLevel, file: file, line: line, pc: pc, fields: Fields{"cmd": name, "stream": "stderr"}}
+}
+
+// captureWriter turns lines into records. exec copies the child output with
+// io.Copy, which uses ReadFrom: the scanner sees EOF and logs a last
+// unterminated line too.
+type captureWriter struct {
+	el      *EasyLogger
+	level   int
+	file    string
+	line    int
+	pc      uintptr
+	fields  Fields
+	partial []byte
+}
+
+func (cw *captureWriter) ReadFrom(r io.Reader) (int64, error) {
+	counter := &countingReader{r: r}
+	scanner := bufio.NewScanner(counter)
+	scanner.Buffer(make([]byte, 0, 64*1024), LOG_MAX_BUFFER_SIZE)
+	for scanner.Scan() {
+		cw.emit(scanner.Bytes())
+	}
+	if err := scanner.Err(); err != nil {
+		// keep draining so the child does not block on a full pipe
+		n, _ := io.Copy(struct{ io.Writer }{cw}, counter)
+		cw.flushPartial()
+		return counter.n + n, err
+	}
+	return counter.n, nil
+}
+
+// Write is used when the output is not copied with ReadFrom.
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	data := append(cw.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		cw.emit(data[:i])
+		data = data[i+1:]
+	}
+	cw.partial = append(cw.partial[:0], data...)
+	if len(cw.partial) >= LOG_MAX_BUFFER_SIZE {
+		cw.flushPartial()
+	}
+	return len(p), nil
+}
+
+func (cw *captureWriter) flushPartial() {
+	if len(cw.partial) > 0 {
+		cw.emit(cw.partial)
+		cw.partial = cw.partial[:0]
+	}
+}
+
+func (cw *captureWriter) emit(line []byte) {
+	if !cw.el.enabled(cw.level) {
+		return
+	}
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	cw.el.record(&Record{Level: cw.level, Time: cw.el.clock.Now(), File: cw.file, Line: cw.line, PC: cw.pc, Message: string(line), Fields: cw.fields.clone()})
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func CaptureCommand(cmd *exec.Cmd, level int) {
+	logger.CaptureCommand(cmd, level)
+}
@@ -0,0 +1,70 @@
+package elog
+
+import (
+	"errors"
+	"os"
+)
+
+// CaptureStdout redirects file descriptor 1 into a pipe and logs every
+// line written to it at level, with the field stream=stdout. It catches what
+// fmt.Print, legacy code and C libraries write. restore puts the original
+// stdout back after the last line was logged. The logger itself must not
+// write to stdout.
+func (el *EasyLogger) CaptureStdout(level int) (restore func() error, err error) {
+	if el.writer == os.Stdout {
+		return nil, errors.New("elog: logger writes to stdout")
+	}
+	file, line, pc := getCaller(el.depth - 1)
+	return el.captureFd(1, &captureWriter{el: el, level: level, file: file, line: line, pc: pc, fields: Fields{"stream": "stdout"}})
+}
+
+// CaptureStderr is CaptureStdout for file descriptor 2, stream=stderr. The
+// logger must not write to stderr, logToStderr included.
+func (el *EasyLogger) CaptureStderr(level int) (restore func() error, err error) {
+	if el.writer == os.Stderr || el.getConfig().logToStderr {
+		return nil, errors.New("elog: logger writes to stderr")
+	}
+	file, line, pc := getCaller(el.depth - 1)
+	return el.captureFd(2, &captureWriter{el: el, level: level, file: file, line: line, pc: pc, fields: Fields{"stream": "stderr"}})
+}
+
+func (el *EasyLogger) captureFd(fd int, cw *captureWriter) (func() error, error) {
+	saved, err := dupFd(fd)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		closeFd(saved)
+		return nil, err
+	}
+	if err := dup2Fd(int(w.Fd()), fd); err != nil {
+		r.Close()
+		w.Close()
+		closeFd(saved)
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cw.ReadFrom(r)
+		r.Close()
+	}()
+	restore := func() error {
+		err := dup2Fd(saved, fd)
+		// both write ends must be closed for the reader to see EOF
+		w.Close()
+		<-done
+		closeFd(saved)
+		return err
+	}
+	return restore, nil
+}
+
+func CaptureStdout(level int) (restore func() error, err error) {
+	return logger.CaptureStdout(level)
+}
+
+func CaptureStderr(level int) (restore func() error, err error) {
+	return logger.CaptureStderr(level)
+}
@@ -0,0 +1,18 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package elog
+
+import "syscall"
+
+func dupFd(fd int) (int, error) {
+	return syscall.Dup(fd)
+}
+
+func dup2Fd(oldfd, newfd int) error {
+	return syscall.Dup2(oldfd, newfd)
+}
+
+func closeFd(fd int) error {
+	return syscall.Close(fd)
+}
@@ -0,0 +1,16 @@
+package elog
+
+import "syscall"
+
+func dupFd(fd int) (int, error) {
+	return syscall.Dup(fd)
+}
+
+// dup2Fd uses dup3, dup2 does not exist on every linux architecture.
+func dup2Fd(oldfd, newfd int) error {
+	return syscall.Dup3(oldfd, newfd, 0)
+}
+
+func closeFd(fd int) error {
+	return syscall.Close(fd)
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package elog
+
+import "errors"
+
+var errCaptureUnsupported = errors.New("elog: stdout/stderr capture not supported on this platform")
+
+func dupFd(fd int) (int, error) {
+	return -1, errCaptureUnsupported
+}
+
+func dup2Fd(oldfd, newfd int) error {
+	return errCaptureUnsupported
+}
+
+func closeFd(fd int) error {
+	return errCaptureUnsupported
+}
@@ -0,0 +1,69 @@
+package elog
+
+import (
+	"sync"
+)
+
+// FileCheckpoint is the position up to which a file handler has flushed
+// its data. An Offset smaller than a saved one for the same Path means the
+// file was rotated, the rest of the old file is in Path + ".1".
+type FileCheckpoint struct {
+	Path   string
+	Offset int64
+}
+
+type checkpointState struct {
+	mutex   sync.Mutex
+	current FileCheckpoint
+	notify  []chan<- FileCheckpoint
+}
+
+// Checkpoint returns the current file and the offset of its flushed data,
+// shippers reading the file can resume from there after a restart.
+func (efh *EasyFileHandler) Checkpoint() FileCheckpoint {
+	efh.checkpoint.mutex.Lock()
+	defer efh.checkpoint.mutex.Unlock()
+	return efh.checkpoint.current
+}
+
+// NotifyCheckpoint sends the new checkpoint to c whenever flushed data or a
+// new file moves it. Sends do not block, c should be buffered; a slow
+// receiver only misses intermediate checkpoints.
+func (efh *EasyFileHandler) NotifyCheckpoint(c chan<- FileCheckpoint) {
+	efh.checkpoint.mutex.Lock()
+	defer efh.checkpoint.mutex.Unlock()
+	efh.checkpoint.notify = append(efh.checkpoint.notify, c)
+}
+
+// StopCheckpoint stops the notifications to c.
+func (efh *EasyFileHandler) StopCheckpoint(c chan<- FileCheckpoint) {
+	efh.checkpoint.mutex.Lock()
+	defer efh.checkpoint.mutex.Unlock()
+	notify := efh.checkpoint.notify[:0]
+	for _, ch := range efh.checkpoint.notify {
+		if ch != c {
+			notify = append(notify, ch)
+		}
+	}
+	efh.checkpoint.notify = notify
+}
+
+func (efh *EasyFileHandler) publishCheckpoint() {
+	cp := FileCheckpoint{
+		Path:   efh.file.Name(),
+		Offset: efh.offset - int64(efh.buffer.Buffered()),
+	}
+	state := &efh.checkpoint
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if cp == state.current {
+		return
+	}
+	state.current = cp
+	for _, c := range state.notify {
+		select {
+		case c <- cp:
+		default:
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package elog
+
+import "time"
+
+// Clock is the source of time for record timestamps, flush ticks and file
+// rotation. Tests can inject a fake clock with WithClock to simulate date
+// rollover and flush intervals without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	ticker *time.Ticker
+}
+
+func (st systemTicker) C() <-chan time.Time {
+	return st.ticker.C
+}
+
+func (st systemTicker) Stop() {
+	st.ticker.Stop()
+}
+
+// WithClock makes the logger, and its handler when it accepts a clock
+// through SetClock, use c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(el *EasyLogger) {
+		el.clock = c
+		if cs, ok := el.writer.(interface{ SetClock(Clock) }); ok {
+			cs.SetClock(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Command elogq filters elog files while streaming them:
+//
+//	elogq -where 'level>=WARN AND fields.user_id=42' -json app-2019-01-01.log
+//
+// Files written by JSONEncoder and TextEncoder are read alike; with no file
+// it reads stdin. A time>= condition seeks with the file index when present.
+// With -merge the files, each with its rotated backups, are merged into one
+// time-ordered stream; -parquet writes that stream as a Parquet file.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/starjiang/elog"
+)
+
+func main() {
+	where := flag.String("where", "", "filter, e.g. level>=WARN AND fields.user_id=42")
+	asJSON := flag.Bool("json", false, "write the matching records as JSON")
+	merge := flag.Bool("merge", false, "merge the files and their rotated backups in time order")
+	parquet := flag.String("parquet", "", "export the merged records to this Parquet file")
+	compression := flag.String("compression", "", "compression of the Parquet pages, gzip or none")
+	flag.Parse()
+	q, err := elog.ParseQuery(*where)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *parquet != "" {
+		if err := export(*parquet, flag.Args(), q, *compression); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	var encoder elog.Encoder = elog.TextEncoder{}
+	if *asJSON {
+		encoder = elog.JSONEncoder{}
+	}
+	write := func(r *elog.Record) error {
+		return encoder.Encode(out, r)
+	}
+	switch {
+	case flag.NArg() == 0:
+		err = elog.QueryRecords(os.Stdin, q, write)
+	case *merge:
+		err = elog.MergeFiles(flag.Args(), q, write)
+	default:
+		for _, path := range flag.Args() {
+			if err = elog.QueryFile(path, q, write); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		out.Flush()
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func export(path string, files []string, q *elog.Query, compression string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	rows, err := elog.ExportParquet(file, files, q, elog.ParquetConfig{Compression: compression})
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "%d rows written to %s\n", rows, path)
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package elog
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Codec compresses the payloads of network handlers. Name is the
+// Content-Encoding token announced to the receiver, e.g. "gzip".
+// gzip and deflate are built in; others such as zstd or snappy are
+// registered with RegisterCodec.
+type Codec interface {
+	Name() string
+	Compress(dst *bytes.Buffer, src []byte) error
+}
+
+var codecMutex sync.Mutex
+var codecs = map[string]Codec{}
+
+func init() {
+	RegisterCodec(NewGzipCodec(gzip.DefaultCompression))
+	RegisterCodec(NewDeflateCodec(flate.DefaultCompression))
+}
+
+// RegisterCodec makes c available to the handlers by its name, replacing
+// a codec of the same name.
+func RegisterCodec(c Codec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	codecs[c.Name()] = c
+}
+
+// LookupCodec returns the codec registered as name, nil for "" and
+// "identity".
+func LookupCodec(name string) (Codec, error) {
+	if name == "" || name == "identity" {
+		return nil, nil
+	}
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	if c, ok := codecs[name]; ok {
+		return c, nil
+	}
+	return nil, errors.New("elog: unknown codec " + strconv.Quote(name))
+}
+
+type writerCodec struct {
+	name      string
+	writers   sync.Pool
+	newWriter func(w io.Writer) (compressWriter, error)
+}
+
+type compressWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// NewGzipCodec returns a gzip codec of the given compress/gzip level.
+func NewGzipCodec(level int) Codec {
+	return &writerCodec{name: "gzip", newWriter: func(w io.Writer) (compressWriter, error) {
+		return gzip.NewWriterLevel(w, level)
+	}}
+}
+
+// NewDeflateCodec returns a codec of zlib-wrapped deflate data, which is
+// what HTTP calls deflate.
+func NewDeflateCodec(level int) Codec {
+	return &writerCodec{name: "deflate", newWriter: func(w io.Writer) (compressWriter, error) {
+		return zlib.NewWriterLevel(w, level)
+	}}
+}
+
+func (wc *writerCodec) Name() string {
+	return wc.name
+}
+
+func (wc *writerCodec) Compress(dst *bytes.Buffer, src []byte) error {
+	cw, ok := wc.writers.Get().(compressWriter)
+	if ok {
+		cw.Reset(dst)
+	} else {
+		var err error
+		if cw, err = wc.newWriter(dst); err != nil {
+			return err
+		}
+	}
+	if _, err := cw.Write(src); err != nil {
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	wc.writers.Put(cw)
+	return nil
+}
@@ -0,0 +1,117 @@
+package elog
+
+import "strconv"
+
+// loggerConfig holds the settings read on every record. It is never
+// modified once stored: updates store a modified copy, so the record path
+// reads it without taking the logger mutex.
+type loggerConfig struct {
+	level       int
+	logToStderr bool
+	encoder     Encoder
+	formatCheck bool
+	sanitize    int
+	development bool
+	eventPolicy int
+	packages    *packageLevels
+	sample      int
+}
+
+func (el *EasyLogger) getConfig() *loggerConfig {
+	return el.config.Load().(*loggerConfig)
+}
+
+func (el *EasyLogger) updateConfig(update func(c *loggerConfig)) {
+	el.updateConfigFrom(LOG_SOURCE_API, update)
+}
+
+// updateConfigFrom applies update and audits the settings it changed.
+func (el *EasyLogger) updateConfigFrom(source string, update func(c *loggerConfig)) {
+	el.configMutex.Lock()
+	before := el.getConfig()
+	config := *before
+	update(&config)
+	el.config.Store(&config)
+	changes := el.audit(diffSettings(before.settings(), config.settings(), source))
+	el.configMutex.Unlock()
+	reportConfigChanges(changes)
+}
+
+// SetLevel changes the minimum level at runtime, level being one of
+// DEBUG, INFO, WARN, ERROR, FATAL and NONE.
+func (el *EasyLogger) SetLevel(level string) {
+	el.setLevel(level, LOG_SOURCE_API)
+}
+
+func (el *EasyLogger) setLevel(level string, source string) {
+	el.updateConfigFrom(source, func(c *loggerConfig) {
+		c.level = getLogLevelInt(level)
+	})
+}
+
+func (el *EasyLogger) GetLevel() string {
+	return getLogLevelString(el.getConfig().level)
+}
+
+func (el *EasyLogger) SetLogToStderr(logToStderr bool) {
+	el.setLogToStderr(logToStderr, LOG_SOURCE_API)
+}
+
+func (el *EasyLogger) setLogToStderr(logToStderr bool, source string) {
+	el.updateConfigFrom(source, func(c *loggerConfig) {
+		c.logToStderr = logToStderr
+	})
+}
+
+func SetLevel(level string) {
+	logger.SetLevel(level)
+}
+
+func GetLevel() string {
+	return logger.GetLevel()
+}
+
+func SetLogToStderr(logToStderr bool) {
+	logger.SetLogToStderr(logToStderr)
+}
+
+type levelFlag struct {
+	el *EasyLogger
+}
+
+func (lf *levelFlag) String() string {
+	if lf.el == nil {
+		// the zero value flag.PrintDefaults asks for the default
+		return getLogLevelString(LOG_LEVEL_INFO)
+	}
+	return lf.el.GetLevel()
+}
+
+func (lf *levelFlag) Set(level string) error {
+	lf.el.setLevel(level, LOG_SOURCE_FLAG)
+	return nil
+}
+
+type stderrFlag struct {
+	el *EasyLogger
+}
+
+func (sf *stderrFlag) String() string {
+	if sf.el == nil {
+		return "false"
+	}
+	return strconv.FormatBool(sf.el.getConfig().logToStderr)
+}
+
+func (sf *stderrFlag) Set(value string) error {
+	logToStderr, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	sf.el.setLogToStderr(logToStderr, LOG_SOURCE_FLAG)
+	return nil
+}
+
+func (sf *stderrFlag) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,28 @@
+package elog
+
+// SetCopyTruncate leaves rotation to an external tool using copytruncate,
+// like logrotate: the handler no longer rotates on size and, when the file
+// shrinks under it, continues at the new end with its counters reset.
+// Truncation is detected on Flush.
+func (efh *EasyFileHandler) SetCopyTruncate(copyTruncate bool) {
+	efh.copyTruncate = copyTruncate
+}
+
+// checkTruncated runs before the buffer is flushed.
+func (efh *EasyFileHandler) checkTruncated() {
+	info, err := efh.file.Stat()
+	if err != nil {
+		return
+	}
+	flushed := efh.offset - int64(efh.buffer.Buffered())
+	if info.Size() >= flushed {
+		return
+	}
+	efh.offset = info.Size() + int64(efh.buffer.Buffered())
+	efh.nbytes = efh.offset
+	efh.stats.setSize(efh.offset)
+	if efh.index != nil && efh.index.file != nil {
+		efh.index.file.Truncate(0)
+		efh.index.next = 0
+	}
+}
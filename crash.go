@@ -0,0 +1,66 @@
+package elog
+
+import (
+	"fmt"
+	"os"
+)
+
+// WithCrashFile makes FATAL records crash safe: besides going through the
+// handler, each one is appended to the crash file at path and fsynced, after
+// the handler was flushed and synced, before Fatal or Panic return control.
+func WithCrashFile(path string) Option {
+	return func(el *EasyLogger) {
+		el.crashPath = path
+	}
+}
+
+// writeCrash runs under the logger mutex after r went to the handler.
+func (el *EasyLogger) writeCrash(r *Record) {
+	el.flushWriters()
+	if syncer, ok := el.writer.(Syncer); ok {
+		syncer.Sync()
+	}
+	file, err := os.OpenFile(el.crashPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		selfLogf(LOG_LEVEL_ERROR, "crash file: %v", err)
+		return
+	}
+	el.getConfig().encoder.Encode(file, r)
+	file.Sync()
+	file.Close()
+}
+
+// Panic logs at FATAL level, flushes the handler and panics with the message.
+// An error or struct given alone is also described by fields, see
+// panicFields.
+func (el *EasyLogger) Panic(args ...interface{}) {
+	el.outputValue(LOG_LEVEL_FATAL, args...)
+	el.Flush()
+	panic(panicMessage(args))
+}
+
+func (el *EasyLogger) Panicf(format string, args ...interface{}) {
+	el.outputf(LOG_LEVEL_FATAL, format, args...)
+	el.Flush()
+	panic(fmt.Sprintf(format, args...))
+}
+
+func (e *Entry) Panic(args ...interface{}) {
+	e.outputValue(LOG_LEVEL_FATAL, args...)
+	e.logger.Flush()
+	panic(panicMessage(args))
+}
+
+func (e *Entry) Panicf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_FATAL, format, args...)
+	e.logger.Flush()
+	panic(fmt.Sprintf(format, args...))
+}
+
+func Panic(args ...interface{}) {
+	logger.Panic(args...)
+}
+
+func Panicf(format string, args ...interface{}) {
+	logger.Panicf(format, args...)
+}
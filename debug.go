@@ -0,0 +1,34 @@
+//go:build !elog_nodebug
+// +build !elog_nodebug
+
+package elog
+
+// LOG_DEBUG_ENABLED is false in binaries built with -tags elog_nodebug,
+// where the Debug methods do nothing. Guarding expensive arguments with it
+// lets the compiler drop the whole call:
+//
+//	if elog.LOG_DEBUG_ENABLED {
+//		elog.Debug("state", dump())
+//	}
+const LOG_DEBUG_ENABLED = true
+
+func (el *EasyLogger) Debug(args ...interface{}) {
+	el.output(LOG_LEVEL_DEBUG, args...)
+}
+func (el *EasyLogger) Debugf(format string, args ...interface{}) {
+	el.outputf(LOG_LEVEL_DEBUG, format, args...)
+}
+
+func (e *Entry) Debug(args ...interface{}) {
+	e.output(LOG_LEVEL_DEBUG, args...)
+}
+func (e *Entry) Debugf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_DEBUG, format, args...)
+}
+
+func Debug(args ...interface{}) {
+	logger.Debug(args...)
+}
+func Debugf(format string, args ...interface{}) {
+	logger.Debugf(format, args...)
+}
@@ -0,0 +1,23 @@
+//go:build elog_nodebug
+// +build elog_nodebug
+
+package elog
+
+// LOG_DEBUG_ENABLED is false: this binary was built with -tags elog_nodebug
+// and the Debug methods are empty, inlined away by the compiler.
+const LOG_DEBUG_ENABLED = false
+
+func (el *EasyLogger) Debug(args ...interface{}) {
+}
+func (el *EasyLogger) Debugf(format string, args ...interface{}) {
+}
+
+func (e *Entry) Debug(args ...interface{}) {
+}
+func (e *Entry) Debugf(format string, args ...interface{}) {
+}
+
+func Debug(args ...interface{}) {
+}
+func Debugf(format string, args ...interface{}) {
+}
@@ -0,0 +1,85 @@
+package elog
+
+import (
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type dedupState struct {
+	window time.Duration
+	last   *Record
+	count  int
+	since  time.Time
+}
+
+// WithDedup collapses runs of identical consecutive records into a single
+// line followed by "last message repeated N times". The summary is written
+// when a different record arrives, on Flush, or once window has elapsed since
+// the first record of the run; a window <= 0 never expires.
+func WithDedup(window time.Duration) Option {
+	return func(el *EasyLogger) {
+		el.dedup = &dedupState{window: window}
+	}
+}
+
+func (ds *dedupState) suppress(el *EasyLogger, r *Record) bool {
+	if ds.last != nil && ds.last.Message == r.Message && ds.last.Level == r.Level && el.sameFields(ds.last.Fields, r.Fields) && !ds.expired(el) {
+		ds.count++
+		return true
+	}
+	ds.release(el)
+	ds.last = r
+	ds.count = 0
+	ds.since = el.clock.Now()
+	return false
+}
+
+// sameFields compares the fields of two records without the seq and id
+// fields that commit stamps after dedup, which differ from record to
+// record.
+func (el *EasyLogger) sameFields(a, b Fields) bool {
+	if !el.sequence && el.recordID == nil {
+		return reflect.DeepEqual(a, b)
+	}
+	for k, v := range a {
+		if el.stamped(k) {
+			continue
+		}
+		if w, ok := b[k]; !ok || !reflect.DeepEqual(v, w) {
+			return false
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok && !el.stamped(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func (el *EasyLogger) stamped(k string) bool {
+	return el.sequence && k == LOG_FIELD_SEQ || el.recordID != nil && k == LOG_FIELD_ID
+}
+
+func (ds *dedupState) expired(el *EasyLogger) bool {
+	return ds.window > 0 && el.clock.Now().Sub(ds.since) >= ds.window
+}
+
+func (ds *dedupState) release(el *EasyLogger) {
+	if ds.count > 0 {
+		summary := *ds.last
+		summary.Time = el.clock.Now()
+		summary.Message = "last message repeated " + strconv.Itoa(ds.count) + " times"
+		summary.Fields = nil
+		el.writeRecord(&summary)
+		ds.last = nil
+		ds.count = 0
+	}
+}
+
+func (ds *dedupState) tick(el *EasyLogger) {
+	if ds.count > 0 && ds.expired(el) {
+		ds.release(el)
+	}
+}
@@ -0,0 +1,86 @@
+package elog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorKey    = "\x1b[36m"
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+)
+
+// DevEncoder is a human oriented encoder for local development: the text
+// header and message followed by one structured field per line, values
+// pretty-printed as indented JSON, keys colored when Color is set.
+type DevEncoder struct {
+	Color bool
+}
+
+func (de DevEncoder) Encode(w io.Writer, r *Record) error {
+	var buf bytes.Buffer
+	header := levelToken(r.Level)
+	if de.Color {
+		if r.Level >= LOG_LEVEL_ERROR {
+			header = colorRed + header + colorReset
+		} else if r.Level >= LOG_LEVEL_WARN {
+			header = colorYellow + header + colorReset
+		}
+	}
+	buf.WriteString(header)
+	if r.Name != "" {
+		buf.WriteString("[" + r.Name + "]")
+	}
+	buf.WriteString(timeToken(r.Time) + callerText(r.File, r.Line))
+	buf.WriteString(r.Message)
+	buf.WriteByte('\n')
+	for _, k := range sortedKeys(r.Fields) {
+		buf.WriteString("    ")
+		if de.Color {
+			buf.WriteString(colorKey + k + colorReset)
+		} else {
+			buf.WriteString(k)
+		}
+		buf.WriteString(": ")
+		buf.WriteString(prettyJSON(r.Fields[k]))
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func prettyJSON(v interface{}) string {
+	var compact bytes.Buffer
+	writeJSONValue(&compact, v)
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact.Bytes(), "    ", "  "); err != nil {
+		return compact.String()
+	}
+	return out.String()
+}
+
+// WithDevelopment switches the logger to development mode: DevEncoder
+// output, colored when stderr looks like a color terminal, and runtime
+// Printf format checks. Leave it out in production to keep compact output.
+func WithDevelopment() Option {
+	return func(el *EasyLogger) {
+		el.updateConfig(func(c *loggerConfig) {
+			c.development = true
+			c.formatCheck = true
+			c.encoder = DevEncoder{Color: colorTerminal()}
+		})
+	}
+}
+
+func colorTerminal() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	term := os.Getenv("TERM")
+	return term != "" && term != "dumb" && !strings.HasPrefix(term, "vt1")
+}
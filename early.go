@@ -0,0 +1,70 @@
+package elog
+
+import (
+	"flag"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	LOG_EARLY_MAX_RECORDS = 1000
+)
+
+// earlyRecords holds the records the global logger gets before flag.Parse,
+// while its level and handler may still change, to replay them once the
+// flags are parsed or Configure is called.
+type earlyRecords struct {
+	replayed int32
+	mutex    sync.Mutex
+	records  []*Record
+	dropped  int
+}
+
+// hold buffers r if the flags are not parsed yet; otherwise it replays the
+// buffer and reports whether the level now filters r out.
+func (er *earlyRecords) hold(el *EasyLogger, r *Record) bool {
+	if atomic.LoadInt32(&er.replayed) != 0 {
+		return false
+	}
+	if flag.Parsed() {
+		el.replayEarly()
+		return !el.enabled(r.Level) || !el.callerEnabled(r.Level, r.PC)
+	}
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+	if atomic.LoadInt32(&er.replayed) != 0 {
+		return false
+	}
+	if len(er.records) >= LOG_EARLY_MAX_RECORDS {
+		er.dropped++
+	} else {
+		er.records = append(er.records, r)
+	}
+	return true
+}
+
+// replayEarly ends buffering and logs the buffered records with the
+// configuration in force now. It is called without the mutex held.
+func (el *EasyLogger) replayEarly() {
+	er := el.early
+	if er == nil || atomic.LoadInt32(&er.replayed) != 0 {
+		return
+	}
+	er.mutex.Lock()
+	if atomic.LoadInt32(&er.replayed) != 0 {
+		er.mutex.Unlock()
+		return
+	}
+	records, dropped := er.records, er.dropped
+	er.records = nil
+	atomic.StoreInt32(&er.replayed, 1)
+	er.mutex.Unlock()
+	for _, r := range records {
+		if el.enabled(r.Level) && el.callerEnabled(r.Level, r.PC) {
+			el.record(r)
+		}
+	}
+	if dropped > 0 {
+		selfLogf(LOG_LEVEL_WARN, "%d records logged before flag.Parse dropped, over %d", dropped, LOG_EARLY_MAX_RECORDS)
+	}
+}
@@ -2,67 +2,210 @@ package elog
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
-	LOG_LEVEL_DEBUG         = 1
-	LOG_LEVEL_INFO          = 2
-	LOG_LEVEL_WARN          = 3
-	LOG_LEVEL_ERROR         = 4
-	LOG_LEVEL_NONE          = 5
+	// The levels are spaced by 10 for RegisterLevel; they were 1 to 6
+	// before, so numeric levels stored elsewhere need converting.
+	LOG_LEVEL_DEBUG         = 10
+	LOG_LEVEL_INFO          = 20
+	LOG_LEVEL_WARN          = 30
+	LOG_LEVEL_ERROR         = 40
+	LOG_LEVEL_FATAL         = 50
+	LOG_LEVEL_NONE          = 60
 	LOG_MAX_FILE_SIZE       = 1024 * 1024 * 1024
 	LOG_MAX_BUFFER_SIZE     = 1024 * 1024
 	LOG_MAX_ROTATE_FILE_NUM = 10
 	LOG_DEPTH_GLOBAL        = 4
 	LOG_DEPTH_HANDLER       = 3
+	LOG_EXIT_CODE_FATAL     = 1
 )
 
 func init() {
 	var logPath string
-	flag.BoolVar(&logger.logToStderr, "logToStderr", false, "log to stderr,default false")
+	logger.config.Store(&loggerConfig{level: LOG_LEVEL_INFO, encoder: TextEncoder{}})
+	flag.Var(&stderrFlag{&logger}, "logToStderr", "log to stderr,default false")
 	flag.IntVar(&logger.flushTime, "logFlushTime", 3, "log flush time interval,default 3 seconds")
-	flag.StringVar(&logger.logLevel, "logLevel", "INFO", "log level[DEBUG,INFO,WARN,ERROR,NONE],default INFO level")
+	flag.Var(&levelFlag{&logger}, "logLevel", "log level[DEBUG,INFO,WARN,ERROR,FATAL,NONE],default INFO level")
 	flag.StringVar(&logPath, "logPath", "./", "log path,default log to current directory")
-	logger.writer = NewEasyFileHandler(logPath, LOG_MAX_BUFFER_SIZE)
+	logger.writer = defaultHandler(logPath)
 	logger.depth = LOG_DEPTH_GLOBAL
-	go logger.flushDaemon()
+	logger.clock = systemClock{}
+	logger.done = make(chan struct{})
+	if requireFlagParse {
+		logger.early = &earlyRecords{}
+	}
+	logger.audited = 1
+	logger.startFlushDaemon()
 }
 
 type EasyLogger struct {
-	mutex       sync.Mutex
-	logToStderr bool
-	flushTime   int
-	logLevel    string
-	writer      EasyLogHandler
-	depth       int
+	closed          int32
+	dumpLevel       int32
+	seq             uint64
+	sampled         uint64
+	locked          int32
+	audited         int32
+	configuring     int32
+	mutex           sync.Mutex
+	config          atomic.Value
+	configMutex     sync.Mutex
+	flushTime       int
+	writer          io.Writer
+	depth           int
+	dedup           *dedupState
+	resolvers       atomic.Value
+	clock           Clock
+	daemonStop      chan struct{}
+	done            chan struct{}
+	synchronous     bool
+	tenancy         *tenancy
+	callSites       *callSiteProfile
+	budget          *callSiteBudget
+	events          atomic.Value
+	burst           *burstState
+	stat            handlerStat
+	stderrStat      handlerStat
+	recent          *recentErrors
+	sequence        bool
+	crashPath       string
+	hooks           atomic.Value
+	histograms      bool
+	routes          []*route
+	handlerEncoders []handlerEncoder
+	idle            *idleFlush
+	async           *asyncQueue
+	spill           *spillQueue
+	recordID        IDGenerator
+	early           *earlyRecords
+	configAudit     configAudit
+
+	reentrantQueue reentrantQueue
 }
 
-func NewEasyLogger(logLevel string, logToStderr bool, flushTime int, writer EasyLogHandler) *EasyLogger {
+type Option func(*EasyLogger)
+
+// NewEasyLogger creates a logger writing to writer. Besides io.Writer the
+// handler may implement any of Flusher, io.Closer, Rotator and Syncer; the
+// logger uses each capability when present.
+func NewEasyLogger(logLevel string, logToStderr bool, flushTime int, writer io.Writer, opts ...Option) *EasyLogger {
 
 	logger := &EasyLogger{}
-	logger.logLevel = logLevel
-	logger.logToStderr = logToStderr
+	logger.config.Store(&loggerConfig{level: getLogLevelInt(logLevel), logToStderr: logToStderr, encoder: TextEncoder{}})
 	logger.flushTime = flushTime
 	logger.writer = writer
 	logger.depth = LOG_DEPTH_HANDLER
-	go logger.flushDaemon()
+	logger.clock = systemClock{}
+	logger.done = make(chan struct{})
+	for _, opt := range opts {
+		opt(logger)
+	}
+	logger.audited = 1
+	logger.startFlushDaemon()
+	register(logger)
 	return logger
 }
 
+// WithSynchronous disables the background flush daemon and flushes the
+// handler after every record, so output is observable as soon as the log
+// call returns.
+func WithSynchronous() Option {
+	return func(el *EasyLogger) {
+		el.synchronous = true
+	}
+}
+
+// Configure applies options to the global logger. The settings and
+// handlers it changes are audited, see ConfigHistory.
+func Configure(opts ...Option) {
+	logger.lock()
+	atomic.StoreInt32(&logger.configuring, 1)
+	handlers := logger.handlerSettings()
+	for _, opt := range opts {
+		opt(&logger)
+	}
+	changes := diffSettings(handlers, logger.handlerSettings(), LOG_SOURCE_CONFIGURE)
+	atomic.StoreInt32(&logger.configuring, 0)
+	logger.configMutex.Lock()
+	for i := range logger.configAudit.pending {
+		logger.configAudit.pending[i].Source = LOG_SOURCE_CONFIGURE
+	}
+	changes = logger.audit(append(logger.configAudit.pending, changes...))
+	logger.configAudit.pending = nil
+	logger.configMutex.Unlock()
+	logger.unlock()
+	reportConfigChanges(changes)
+	logger.startFlushDaemon()
+	logger.replayEarly()
+}
+
 type EasyLogHandler interface {
 	io.Writer
 	Flush()
 }
 
+// Flusher writes out data buffered by a handler.
+type Flusher interface {
+	Flush()
+}
+
+// CheckedFlusher is implemented by handlers whose flush can fail. The
+// logger calls FlushErr instead of Flush for them and reports its error
+// from Flush.
+type CheckedFlusher interface {
+	FlushErr() error
+}
+
+// Rotator starts a new log file, keeping the current one as a backup.
+type Rotator interface {
+	Rotate() error
+}
+
+// RecordWriter is implemented by handlers that need the record itself, e.g.
+// its level or fields, next to its encoded form. The logger calls
+// WriteRecord instead of Write for them; p is only valid during the call
+// and, being shared with the other handlers, must not be modified.
+type RecordWriter interface {
+	WriteRecord(r *Record, p []byte) (int, error)
+}
+
+// Syncer commits written data to stable storage.
+type Syncer interface {
+	Sync() error
+}
+
+func flushHandler(w io.Writer) error {
+	if flusher, ok := w.(CheckedFlusher); ok {
+		return flusher.FlushErr()
+	}
+	if flusher, ok := w.(Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func closeHandler(w io.Writer) error {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	if closer, ok := w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
 func NewEasyFileHandler(path string, bufferSize int) *EasyFileHandler {
 	handler := &EasyFileHandler{}
 	handler.path = path
@@ -70,42 +213,213 @@ func NewEasyFileHandler(path string, bufferSize int) *EasyFileHandler {
 	handler.buffer = nil
 	handler.currentDate = ""
 	handler.bufferSize = bufferSize
+	handler.clock = systemClock{}
+	handler.ext = ".log"
 	return handler
 }
 
 type EasyFileHandler struct {
-	path        string
-	file        *os.File
-	buffer      *bufio.Writer
-	bufferSize  int
-	currentDate string
-	nbytes      int
+	path          string
+	file          *os.File
+	buffer        *bufio.Writer
+	bufferSize    int
+	currentDate   string
+	nbytes        int64
+	maxSize       int64
+	clock         Clock
+	offset        int64
+	index         *fileIndex
+	checkpoint    checkpointState
+	ext           string
+	template      string
+	stats         fileStats
+	copyTruncate  bool
+	owner         *fileOwner
+	symlinkCheck  bool
+	symlinkDir    string
+	header        bool
+	version       string
+	headerPending bool
+	tempDir       string
+	keepTempDir   bool
+}
+
+func (efh *EasyFileHandler) SetClock(c Clock) {
+	efh.clock = c
+}
+
+// SetExtension replaces the ".log" file extension, e.g. to keep two handlers
+// apart in one directory.
+func (efh *EasyFileHandler) SetExtension(ext string) {
+	efh.ext = ext
+}
+
+func (efh *EasyFileHandler) fileName(date string) string {
+	if efh.template != "" {
+		return efh.path + "/" + strings.Replace(efh.template, "{date}", date, -1)
+	}
+	return efh.path + "/" + getAppName() + "-" + date + efh.ext
+}
+
+// SetMaxSize sets the size at which the file is rotated, LOG_MAX_FILE_SIZE
+// by default. A file never grows past it unless a single record is larger.
+func (efh *EasyFileHandler) SetMaxSize(size int64) {
+	efh.maxSize = size
 }
 
 func (efh *EasyFileHandler) Write(data []byte) (int, error) {
 
-	err := efh.rotateFile()
+	err := efh.rotateFile(len(data))
 
 	if err != nil {
-		os.Stderr.WriteString(err.Error() + "\n")
+		selfLogf(LOG_LEVEL_ERROR, "file handler: %v", err)
 		return 0, err
 	}
-	efh.nbytes += len(data)
+	if efh.headerPending {
+		efh.writeHeader(data)
+	}
+	if efh.index != nil {
+		efh.index.mark(efh.clock.Now(), efh.offset, data)
+	}
+	efh.countWrite(len(data))
+	// a record that does not fit the buffer is never split across two
+	// writes to the file: the buffer goes out first
+	if len(data) > efh.buffer.Available() && efh.buffer.Buffered() > 0 {
+		if err := efh.buffer.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	efh.nbytes += int64(len(data))
+	efh.offset += int64(len(data))
 	return efh.buffer.Write(data)
 
 }
 
 func (efh *EasyFileHandler) Flush() {
+	efh.FlushErr()
+}
+
+func (efh *EasyFileHandler) FlushErr() error {
+	if efh.file == nil {
+		return nil
+	}
+	if efh.copyTruncate {
+		efh.checkTruncated()
+	}
+	err := efh.buffer.Flush()
+	//efh.file.Sync()
+	efh.publishCheckpoint()
+	efh.tuneBuffer()
+	return err
+}
+
+func (efh *EasyFileHandler) Close() error {
+	err := efh.closeFile()
+	if rerr := efh.removeTempDir(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// closeFile flushes and closes the current file, leaving the directory.
+func (efh *EasyFileHandler) closeFile() error {
+	if efh.file == nil {
+		return nil
+	}
+	err := efh.buffer.Flush()
+	if cerr := efh.file.Close(); err == nil {
+		err = cerr
+	}
+	efh.file = nil
+	if efh.index != nil {
+		efh.index.close()
+	}
+	return err
+}
+
+func (efh *EasyFileHandler) Sync() error {
+	if efh.file == nil {
+		return nil
+	}
+	if err := efh.buffer.Flush(); err != nil {
+		return err
+	}
+	efh.publishCheckpoint()
+	return efh.file.Sync()
+}
+
+// Rotate closes the current file and shifts the backups, the next write
+// starts a new file.
+func (efh *EasyFileHandler) Rotate() error {
+	date := efh.currentDate
+	if date == "" {
+		date = efh.clock.Now().Format("2006-01-02")
+	}
+	return efh.rotate(date)
+}
+
+func (efh *EasyFileHandler) rotate(date string) error {
+	var err error
 	if efh.file != nil {
 		efh.buffer.Flush()
-		//efh.file.Sync()
+		err = efh.file.Close()
+		if err != nil {
+			return err
+		}
+		efh.file = nil
+		if efh.index != nil {
+			efh.index.close()
+		}
+	}
+
+	// the file is closed before it is renamed, which Windows requires
+	logFilePath := efh.fileName(date) + "." + strconv.Itoa(LOG_MAX_ROTATE_FILE_NUM-1)
+	if fileIsExist(logFilePath) {
+		err = retryFileOp(func() error {
+			return os.Remove(logFilePath)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	os.Remove(logFilePath + LOG_INDEX_SUFFIX)
+
+	for i := LOG_MAX_ROTATE_FILE_NUM - 2; i >= 0; i-- {
+		var logFilePath string
+		if i == 0 {
+			logFilePath = efh.fileName(date)
+		} else {
+			logFilePath = efh.fileName(date) + "." + strconv.Itoa(i)
+		}
+		if fileIsExist(logFilePath) {
+			logFileNewPath := efh.fileName(date) + "." + strconv.Itoa(i+1)
+			// the index moves with its file; a retry skips what was done
+			err := retryFileOp(func() error {
+				if fileIsExist(logFilePath) {
+					if err := os.Rename(logFilePath, logFileNewPath); err != nil {
+						return err
+					}
+				}
+				if fileIsExist(logFilePath + LOG_INDEX_SUFFIX) {
+					return os.Rename(logFilePath+LOG_INDEX_SUFFIX, logFileNewPath+LOG_INDEX_SUFFIX)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
 	}
+	selfLogf(LOG_LEVEL_INFO, "rotated %s", efh.fileName(date))
+	return nil
 }
 
-func (efh *EasyFileHandler) rotateFile() error {
+// rotateFile opens the file for a write of n bytes, starting a new one when
+// the date changed or n bytes would take the file past its maximum size.
+func (efh *EasyFileHandler) rotateFile(n int) error {
 
 	var err error
-	date := getTimeNowDate()
+	date := efh.clock.Now().Format("2006-01-02")
 
 	if efh.currentDate != date {
 		if efh.file != nil {
@@ -115,83 +429,65 @@ func (efh *EasyFileHandler) rotateFile() error {
 				return err
 			}
 			efh.file = nil
+			if efh.index != nil {
+				efh.index.close()
+			}
 		}
 		efh.currentDate = date
+		efh.nbytes = 0
+		if info, err := os.Stat(efh.fileName(date)); err == nil {
+			efh.nbytes = info.Size()
+		}
 	}
 
-	if efh.nbytes > LOG_MAX_FILE_SIZE {
-		appName := getAppName()
-		efh.buffer.Flush()
-		err = efh.file.Close()
+	maxSize := efh.maxSize
+	if maxSize <= 0 {
+		maxSize = LOG_MAX_FILE_SIZE
+	}
+	if efh.nbytes > 0 && efh.nbytes+int64(n) > maxSize && !efh.copyTruncate {
+		err = efh.rotate(date)
 		if err != nil {
 			return err
 		}
+	}
 
-		efh.file = nil
-
-		logFilePath := efh.path + "/" + appName + "-" + date + ".log." + strconv.Itoa(LOG_MAX_ROTATE_FILE_NUM-1)
-		if fileIsExist(logFilePath) {
-			err = os.Remove(logFilePath)
-			if err != nil {
+	if efh.file == nil {
+		logFilePath := efh.fileName(date)
+		if efh.template != "" {
+			if err = os.MkdirAll(filepath.Dir(logFilePath), 0755); err != nil {
 				return err
 			}
 		}
-
-		for i := LOG_MAX_ROTATE_FILE_NUM - 2; i >= 0; i-- {
-			var logFilePath string
-			if i == 0 {
-				logFilePath = efh.path + "/" + appName + "-" + date + ".log"
-			} else {
-				logFilePath = efh.path + "/" + appName + "-" + date + ".log." + strconv.Itoa(i)
-			}
-			if fileIsExist(logFilePath) {
-				logFileNewPath := efh.path + "/" + appName + "-" + date + ".log." + strconv.Itoa(i+1)
-				err := os.Rename(logFilePath, logFileNewPath)
-				if err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	if efh.file == nil {
-		logFilePath := efh.path + "/" + getAppName() + "-" + date + ".log"
-		efh.file, err = os.OpenFile(logFilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		efh.file, err = efh.openFile(logFilePath)
 		if err != nil {
 			return err
 		}
-		efh.nbytes = 0
+		efh.offset = 0
+		if info, err := efh.file.Stat(); err == nil {
+			efh.offset = info.Size()
+		}
+		efh.nbytes = efh.offset
+		efh.stats.setSize(efh.offset)
+		if efh.index != nil {
+			efh.index.open(logFilePath+LOG_INDEX_SUFFIX, efh.offset)
+		}
 		efh.buffer = bufio.NewWriterSize(efh.file, efh.bufferSize)
+		efh.headerPending = efh.header && efh.offset == 0
+		efh.publishCheckpoint()
 	}
 	return nil
 }
 
 func getLogLevelInt(level string) int {
-	if level == "DEBUG" {
-		return LOG_LEVEL_DEBUG
-	} else if level == "INFO" {
-		return LOG_LEVEL_INFO
-	} else if level == "WARN" {
-		return LOG_LEVEL_WARN
-	} else if level == "ERROR" {
-		return LOG_LEVEL_ERROR
-	} else if level == "NONE" {
-		return LOG_LEVEL_NONE
+	if value, ok := currentLevels().values[level]; ok {
+		return value
 	}
 	return LOG_LEVEL_INFO
 }
 
 func getLogLevelString(level int) string {
-	if level == LOG_LEVEL_DEBUG {
-		return "DEBUG"
-	} else if level == LOG_LEVEL_INFO {
-		return "INFO"
-	} else if level == LOG_LEVEL_WARN {
-		return "WARN"
-	} else if level == LOG_LEVEL_ERROR {
-		return "ERROR"
-	} else if level == LOG_LEVEL_NONE {
-		return "NONE"
+	if name, ok := currentLevels().names[level]; ok {
+		return name
 	}
 	return "INFO"
 }
@@ -205,76 +501,330 @@ func getAppName() string {
 	return appName
 }
 
-func (el *EasyLogger) getHeader(level int, writer io.Writer) {
+// Record is a single log event as handed to an Encoder.
+type Record struct {
+	Level   int
+	Name    string
+	Time    time.Time
+	File    string
+	Line    int
+	PC      uintptr // program counter of the call site, 0 when unknown
+	Message string
+	Fields  Fields
+	Context context.Context
+}
+
+func getCaller(depth int) (string, int, uintptr) {
 
-	_, file, line, ok := runtime.Caller(el.depth)
+	pc, file, line, ok := runtime.Caller(depth)
 
 	if !ok {
 		file = "???"
 		line = 1
 	} else {
-		slash := strings.LastIndex(file, "/")
-		if slash >= 0 {
-			file = file[slash+1:]
-		}
+		file = shortFileName(file)
 	}
-	fmt.Fprintf(writer, "[%s][%s][file:%s line:%d] ", getLogLevelString(level), getTimeNowStr(), file, line)
-	if el.logToStderr {
-		fmt.Fprintf(os.Stderr, "[%s][%s][file:%s line:%d] ", getLogLevelString(level), getTimeNowStr(), file, line)
+	return file, line, pc
+}
+
+func (el *EasyLogger) enabled(level int) bool {
+	if atomic.LoadInt32(&el.closed) != 0 {
+		return false
+	}
+	// every level is held before flag.Parse, -logLevel may lower it
+	if el.early != nil && atomic.LoadInt32(&el.early.replayed) == 0 {
+		return true
 	}
+	config := el.getConfig()
+	if config.packages != nil && config.packages.min < config.level {
+		return level >= config.packages.min
+	}
+	return level >= config.level
 }
 
 func (el *EasyLogger) output(level int, args ...interface{}) {
 
-	if el.depth == LOG_DEPTH_GLOBAL && !flag.Parsed() {
-		os.Stderr.Write([]byte("ERROR: logging before flag.Parse\n"))
+	if !el.enabled(level) {
 		return
 	}
-	if level < getLogLevelInt(el.logLevel) {
+	file, line, pc := getCaller(el.depth)
+	if !el.callerEnabled(level, pc) {
 		return
 	}
-	el.mutex.Lock()
-	defer el.mutex.Unlock()
-	el.getHeader(level, el.writer)
-	fmt.Fprintln(el.writer, args...)
-	if el.logToStderr {
-		fmt.Fprintln(os.Stderr, args...)
-	}
+	el.record(&Record{Level: level, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: sprintln(args...)})
 }
 
 func (el *EasyLogger) outputf(level int, format string, args ...interface{}) {
 
-	if el.depth == LOG_DEPTH_GLOBAL && !flag.Parsed() {
-		os.Stderr.Write([]byte("ERROR: logging before flag.Parse\n"))
+	if !el.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(el.depth)
+	if !el.callerEnabled(level, pc) {
+		return
+	}
+	el.record(&Record{Level: level, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: el.sprintf(file, line, format, args...)})
+}
+
+func sprintln(args ...interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
+
+func (el *EasyLogger) record(r *Record) {
+	if el.early != nil && el.early.hold(el, r) {
+		return
+	}
+	config := el.getConfig()
+	if el.sampledOut(config, r.Level) {
+		return
+	}
+	el.resolveFields(r)
+	if !el.runHooks(r) {
+		return
+	}
+	if mode := config.sanitize; mode != LOG_SANITIZE_NONE {
+		sanitizeRecord(r, mode)
+	}
+	stack := el.stackRecord(r)
+	if s := scopeFromContext(r.Context); s != nil && s.hold(el, r, stack) {
+		return
+	}
+	if el.reentrant(r, stack) {
 		return
 	}
-	if level < getLogLevelInt(el.logLevel) {
+	el.lock()
+	defer el.unlock()
+	if atomic.LoadInt32(&el.closed) != 0 {
 		return
 	}
+	el.commit(r, stack)
+}
 
-	el.mutex.Lock()
-	defer el.mutex.Unlock()
+// commit writes r and its stack record, if any, with the mutex held.
+func (el *EasyLogger) commit(r, stack *Record) {
+	if el.dedup != nil && el.dedup.suppress(el, r) {
+		return
+	}
+	if el.sequence {
+		if r.Fields == nil {
+			r.Fields = Fields{}
+		}
+		r.Fields[LOG_FIELD_SEQ] = atomic.AddUint64(&el.seq, 1)
+	}
+	if el.recordID != nil {
+		if r.Fields == nil {
+			r.Fields = Fields{}
+		}
+		r.Fields[LOG_FIELD_ID] = el.recordID(r.Time)
+	}
+	if el.async != nil {
+		el.async.write(el, r, stack)
+	} else {
+		el.writeQueued(asyncRecord{r, stack})
+	}
+	if el.crashPath != "" && r.Level >= LOG_LEVEL_FATAL {
+		el.writeCrash(r)
+	}
+	if el.burst != nil {
+		el.burst.add()
+	}
+	if el.recent != nil && r.Level >= LOG_LEVEL_ERROR {
+		el.recent.add(r)
+	}
+	if el.synchronous {
+		el.flushWriters()
+	} else if el.idle != nil {
+		el.idle.touch()
+	}
+}
 
-	el.getHeader(level, el.writer)
-	fmt.Fprintf(el.writer, format, args...)
-	el.writer.Write([]byte("\n"))
-	if el.logToStderr {
-		fmt.Fprintf(os.Stderr, format, args...)
-		os.Stderr.WriteString("\n")
+func (el *EasyLogger) writeRecord(r *Record) {
+	config := el.getConfig()
+	// the record is encoded once per encoder and every handler using that
+	// encoder gets the same bytes
+	encodings := recordEncodings{r: r}
+	defer encodings.release()
+	if el.routes != nil && el.routeRecord(config, r, &encodings) {
+		return
+	}
+	writer, stat := el.writer, &el.stat
+	if el.tenancy != nil {
+		if writer, stat = el.tenancy.handler(r, el.writer, &el.stat); writer == nil {
+			return
+		}
+	}
+	// the whole record is encoded first and handed over in one counted
+	// write, so handlers account and rotate on complete records
+	start := el.startTimer()
+	buf, err := encodings.get(el.encoderFor(writer, config))
+	if err != nil {
+		stat.add(el, 0, err, start)
+		return
+	}
+	if el.budget != nil && !el.budget.allow(el, r, int64(buf.Len())) {
+		return
+	}
+	n, err := el.writeEncoded(writer, r, buf.Bytes())
+	stat.add(el, int64(n), err, start)
+	if el.callSites != nil && el.callSites.sample() {
+		el.callSites.add(r, int64(buf.Len()))
+	}
+	if config.logToStderr {
+		start := el.startTimer()
+		if buf, err = encodings.get(el.encoderFor(os.Stderr, config)); err == nil {
+			n, err = os.Stderr.Write(buf.Bytes())
+		}
+		el.stderrStat.add(el, int64(n), err, start)
+	}
+}
+
+// writeEncoded hands an encoded record to w in one write.
+func writeEncoded(w io.Writer, r *Record, data []byte) (int, error) {
+	if recordWriter, ok := w.(RecordWriter); ok {
+		return recordWriter.WriteRecord(r, data)
+	}
+	return w.Write(data)
+}
+
+// Flush writes out the buffered records and flushes the handlers. It
+// returns the first error of a handler since the previous flush, of a
+// write or of the flush itself.
+func (el *EasyLogger) Flush() error {
+	return el.FlushContext(context.Background())
+}
+
+// FlushContext is Flush bounded by ctx, for shutdown paths that must not
+// hang on a slow handler: it returns ctx.Err() once ctx is done, leaving
+// the flush to finish in the background.
+func (el *EasyLogger) FlushContext(ctx context.Context) error {
+	if ctx.Done() == nil {
+		return el.flush()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- el.flush()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (el *EasyLogger) flush() error {
+	el.replayEarly()
+	el.lock()
+	if el.dedup != nil {
+		el.dedup.release(el)
+	}
+	if el.budget != nil {
+		el.budget.tick(el)
+	}
+	if el.async != nil {
+		el.async.drain(el, 0)
+	}
+	err := el.flushWriters()
+	el.unlock()
+	return err
+}
+
+// Rotate asks the handler to start a new file if it implements Rotator.
+func (el *EasyLogger) Rotate() error {
+	el.lock()
+	defer el.unlock()
+	if rotator, ok := el.writer.(Rotator); ok {
+		return rotator.Rotate()
+	}
+	return nil
+}
+
+// Sync flushes the handler and commits its data to stable storage if it
+// implements Syncer.
+func (el *EasyLogger) Sync() error {
+	el.lock()
+	defer el.unlock()
+	if el.async != nil {
+		el.async.drain(el, 0)
+	}
+	err := el.flushWriters()
+	if syncer, ok := el.writer.(Syncer); ok {
+		if serr := syncer.Sync(); err == nil {
+			err = serr
+		}
 	}
+	return err
 }
 
-func (el *EasyLogger) Flush() {
-	el.mutex.Lock()
-	el.writer.Flush()
-	el.mutex.Unlock()
+// flushWriters flushes every handler and returns the first error.
+func (el *EasyLogger) flushWriters() error {
+	if el.spill != nil {
+		el.spill.replay()
+	}
+	err := el.stat.flush(el, el.writer)
+	if el.tenancy != nil {
+		if terr := el.tenancy.flush(el); err == nil {
+			err = terr
+		}
+	}
+	for _, rt := range el.routeHandlers() {
+		if rerr := rt.stat.flush(el, rt.Handler); err == nil {
+			err = rerr
+		}
+	}
+	return err
 }
 
-func (el *EasyLogger) Debug(args ...interface{}) {
-	el.output(LOG_LEVEL_DEBUG, args...)
+func (el *EasyLogger) closeWriters() error {
+	err := closeHandler(el.writer)
+	if el.tenancy != nil {
+		if terr := el.tenancy.close(); err == nil {
+			err = terr
+		}
+	}
+	for _, rt := range el.routeHandlers() {
+		if rerr := closeHandler(rt.Handler); err == nil {
+			err = rerr
+		}
+	}
+	return err
 }
-func (el *EasyLogger) Debugf(format string, args ...interface{}) {
-	el.outputf(LOG_LEVEL_DEBUG, format, args...)
+
+// Shutdown stops accepting new records, writes out everything buffered and
+// closes the handlers implementing io.Closer. It returns the error of
+// closing or flushing the handlers, or ctx.Err() if ctx expires first; the
+// drain then keeps going in the background.
+func (el *EasyLogger) Shutdown(ctx context.Context) error {
+	el.replayEarly()
+	if !atomic.CompareAndSwapInt32(&el.closed, 0, 1) {
+		return nil
+	}
+	close(el.done)
+	unregister(el)
+	done := make(chan error, 1)
+	go func() {
+		el.lock()
+		defer el.unlock()
+		el.stopFlushDaemon()
+		if el.dedup != nil {
+			el.dedup.release(el)
+		}
+		if el.async != nil {
+			el.async.drain(el, 0)
+		}
+		err := el.flushWriters()
+		if cerr := el.closeWriters(); cerr != nil {
+			err = cerr
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (el *EasyLogger) Info(args ...interface{}) {
@@ -299,6 +849,41 @@ func (el *EasyLogger) Errorf(format string, args ...interface{}) {
 	el.outputf(LOG_LEVEL_ERROR, format, args...)
 }
 
+func (el *EasyLogger) Fatal(args ...interface{}) {
+	el.outputValue(LOG_LEVEL_FATAL, args...)
+	el.exit(LOG_EXIT_CODE_FATAL)
+}
+
+func (el *EasyLogger) Fatalf(format string, args ...interface{}) {
+	el.outputf(LOG_LEVEL_FATAL, format, args...)
+	el.exit(LOG_EXIT_CODE_FATAL)
+}
+
+func (el *EasyLogger) FatalCode(code int, args ...interface{}) {
+	el.outputValue(LOG_LEVEL_FATAL, args...)
+	el.exit(code)
+}
+
+func (el *EasyLogger) FatalCodef(code int, format string, args ...interface{}) {
+	el.outputf(LOG_LEVEL_FATAL, format, args...)
+	el.exit(code)
+}
+
+func (el *EasyLogger) exit(code int) {
+	el.Flush()
+	getExitFunc()(code)
+}
+
+// Log logs at the level named levelName, built-in or registered with
+// RegisterLevel. Unknown names log at INFO.
+func (el *EasyLogger) Log(levelName string, args ...interface{}) {
+	el.output(getLogLevelInt(levelName), args...)
+}
+
+func (el *EasyLogger) Logf(levelName string, format string, args ...interface{}) {
+	el.outputf(getLogLevelInt(levelName), format, args...)
+}
+
 func (el *EasyLogger) Println(args ...interface{}) {
 	el.output(LOG_LEVEL_INFO, args...)
 }
@@ -307,19 +892,66 @@ func (el *EasyLogger) Printf(format string, args ...interface{}) {
 	el.outputf(LOG_LEVEL_INFO, format, args...)
 }
 
-func (el *EasyLogger) flushDaemon() {
-	for _ = range time.NewTicker(time.Second * time.Duration(el.flushTime)).C {
-		el.Flush()
+func (el *EasyLogger) startFlushDaemon() {
+	el.lock()
+	defer el.unlock()
+	el.stopFlushDaemon()
+	if el.synchronous || atomic.LoadInt32(&el.closed) != 0 {
+		return
+	}
+	el.daemonStop = make(chan struct{})
+	go el.flushDaemon(el.clock.NewTicker(time.Second*time.Duration(el.flushTime)), el.daemonStop)
+}
+
+func (el *EasyLogger) stopFlushDaemon() {
+	if el.daemonStop != nil {
+		close(el.daemonStop)
+		el.daemonStop = nil
+	}
+}
+
+func (el *EasyLogger) flushDaemon(ticker Ticker, stop chan struct{}) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+		case <-stop:
+			return
+		}
+		el.lock()
+		if el.dedup != nil {
+			el.dedup.tick(el)
+		}
+		if el.budget != nil {
+			el.budget.tick(el)
+		}
+		if el.burst == nil || el.burst.tick(el) {
+			el.flushWriters()
+		}
+		el.unlock()
 	}
 }
 
 var logger EasyLogger
 
-func Debug(args ...interface{}) {
-	logger.Debug(args...)
+var exitMutex sync.Mutex
+var exitFunc = os.Exit
+
+// SetExitFunc replaces the function called by Fatal* after the log is flushed,
+// os.Exit by default. Passing nil restores os.Exit.
+func SetExitFunc(fn func(int)) {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitFunc = fn
 }
-func Debugf(format string, args ...interface{}) {
-	logger.Debugf(format, args...)
+
+func getExitFunc() func(int) {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+	return exitFunc
 }
 
 func Info(args ...interface{}) {
@@ -344,6 +976,27 @@ func Errorf(format string, args ...interface{}) {
 	logger.Errorf(format, args...)
 }
 
+func Fatal(args ...interface{}) {
+	logger.Fatal(args...)
+}
+func Fatalf(format string, args ...interface{}) {
+	logger.Fatalf(format, args...)
+}
+
+func FatalCode(code int, args ...interface{}) {
+	logger.FatalCode(code, args...)
+}
+func FatalCodef(code int, format string, args ...interface{}) {
+	logger.FatalCodef(code, format, args...)
+}
+
+func Log(levelName string, args ...interface{}) {
+	logger.Log(levelName, args...)
+}
+func Logf(levelName string, format string, args ...interface{}) {
+	logger.Logf(levelName, format, args...)
+}
+
 func Println(args ...interface{}) {
 	logger.Println(args...)
 }
@@ -351,8 +1004,24 @@ func Printf(format string, args ...interface{}) {
 	logger.Printf(format, args...)
 }
 
-func Flush() {
-	logger.Flush()
+func Flush() error {
+	return logger.Flush()
+}
+
+func FlushContext(ctx context.Context) error {
+	return logger.FlushContext(ctx)
+}
+
+func Shutdown(ctx context.Context) error {
+	return logger.Shutdown(ctx)
+}
+
+func Rotate() error {
+	return logger.Rotate()
+}
+
+func Sync() error {
+	return logger.Sync()
 }
 
 func getTimeNow() int64 {
@@ -360,7 +1029,11 @@ func getTimeNow() int64 {
 }
 
 func getTimeNowStr() string {
-	return time.Now().Format("2006-01-02 15:04:05")
+	return formatTime(time.Now())
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
 }
 
 func getTimeNowDate() string {
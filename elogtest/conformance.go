@@ -0,0 +1,345 @@
+package elogtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/starjiang/elog"
+)
+
+// Harness connects RunHandlerConformance to a handler and the destination
+// it writes to: a file, a TCPServer, or a Kafka or Loki container started
+// by the test.
+type Harness struct {
+	// New returns the handler under test, writing to an empty destination.
+	New func(t *testing.T) io.Writer
+	// Received returns what reached the destination so far, decoded to the
+	// handler's record encoding, e.g. the lines of a file or the messages
+	// of a topic one per line.
+	Received func(t *testing.T) []byte
+	// Break makes the destination unavailable and Restore brings it back;
+	// the failure test is skipped without them.
+	Break   func(t *testing.T)
+	Restore func(t *testing.T)
+	// Timeout bounds waiting for records and for calls that may block on
+	// the destination, 10s by default.
+	Timeout time.Duration
+}
+
+// RunHandlerConformance checks that the handler made by h behaves as the
+// logger expects: records arrive complete, once and in order, Flush
+// delivers what was written, Shutdown closes cleanly and a handler whose
+// destination fails neither blocks nor panics and recovers when it is
+// back.
+func RunHandlerConformance(t *testing.T, h Harness) {
+	if h.Timeout <= 0 {
+		h.Timeout = 10 * time.Second
+	}
+	run := &conformance{h: h, run: time.Now().UnixNano()}
+	t.Run("Ordering", run.ordering)
+	t.Run("Flush", run.flush)
+	t.Run("Concurrent", run.concurrent)
+	t.Run("Close", run.close)
+	t.Run("Failure", run.failure)
+}
+
+type conformance struct {
+	h   Harness
+	run int64
+}
+
+// marker returns the message of record i of test, unique across runs and
+// never the prefix of another.
+func (c *conformance) marker(test string, i int) string {
+	return fmt.Sprintf("elogtest-%d-%s-%06d", c.run, test, i)
+}
+
+// logger returns a logger that only flushes when asked to.
+func (c *conformance) logger(t *testing.T) *elog.EasyLogger {
+	return elog.NewEasyLogger("DEBUG", false, 3600, c.h.New(t))
+}
+
+func (c *conformance) shutdown(t *testing.T, el *elog.EasyLogger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.h.Timeout)
+	defer cancel()
+	return el.Shutdown(ctx)
+}
+
+// within runs fn, failing t when it panics or takes longer than the
+// timeout.
+func (c *conformance) within(t *testing.T, what string, fn func()) {
+	t.Helper()
+	done := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			done <- recover()
+		}()
+		fn()
+	}()
+	timer := time.NewTimer(c.h.Timeout)
+	defer timer.Stop()
+	select {
+	case p := <-done:
+		if p != nil {
+			t.Fatalf("%s panicked: %v", what, p)
+		}
+	case <-timer.C:
+		t.Fatalf("%s blocked for more than %v", what, c.h.Timeout)
+	}
+}
+
+// wait polls the destination until it holds every marker and returns the
+// received data, failing t with the missing ones at the timeout.
+func (c *conformance) wait(t *testing.T, markers []string) []byte {
+	t.Helper()
+	deadline := time.Now().Add(c.h.Timeout)
+	for {
+		data := c.h.Received(t)
+		var missing []string
+		for _, m := range markers {
+			if !bytes.Contains(data, []byte(m)) {
+				missing = append(missing, m)
+			}
+		}
+		if len(missing) == 0 {
+			return data
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d records not received after %v, first %s", len(missing), len(markers), c.h.Timeout, missing[0])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *conformance) ordering(t *testing.T) {
+	el := c.logger(t)
+	defer c.shutdown(t, el)
+	markers := make([]string, 100)
+	for i := range markers {
+		markers[i] = c.marker("ordering", i)
+		el.Info(markers[i])
+	}
+	c.within(t, "Flush", func() { el.Flush() })
+	data := c.wait(t, markers)
+	last := -1
+	for _, m := range markers {
+		at := bytes.Index(data, []byte(m))
+		if at < last {
+			t.Fatalf("%s received out of order", m)
+		}
+		last = at
+	}
+}
+
+func (c *conformance) flush(t *testing.T) {
+	el := c.logger(t)
+	defer c.shutdown(t, el)
+	for i := 0; i < 3; i++ {
+		m := c.marker("flush", i)
+		el.Warn(m)
+		c.within(t, "Flush", func() { el.Flush() })
+		c.wait(t, []string{m})
+	}
+}
+
+func (c *conformance) concurrent(t *testing.T) {
+	el := c.logger(t)
+	defer c.shutdown(t, el)
+	const writers, records = 8, 50
+	markers := make([]string, writers*records)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				m := c.marker("concurrent", w*records+i)
+				markers[w*records+i] = m
+				el.Info(m, "from", w)
+			}
+		}(w)
+	}
+	wg.Wait()
+	c.within(t, "Flush", func() { el.Flush() })
+	data := c.wait(t, markers)
+	for _, m := range markers {
+		if n := bytes.Count(data, []byte(m)); n != 1 {
+			t.Fatalf("%s received %d times", m, n)
+		}
+	}
+}
+
+func (c *conformance) close(t *testing.T) {
+	handler := c.h.New(t)
+	el := elog.NewEasyLogger("DEBUG", false, 3600, handler)
+	markers := []string{c.marker("close", 0), c.marker("close", 1)}
+	for _, m := range markers {
+		el.Error(m)
+	}
+	c.within(t, "Shutdown", func() {
+		if err := c.shutdown(t, el); err != nil {
+			t.Errorf("Shutdown: %v", err)
+		}
+	})
+	c.wait(t, markers)
+	if closer, ok := handler.(io.Closer); ok {
+		c.within(t, "second Close", func() {
+			closer.Close()
+		})
+	}
+	c.within(t, "Write after Close", func() {
+		handler.Write([]byte(c.marker("close", 2) + "\n"))
+	})
+}
+
+func (c *conformance) failure(t *testing.T) {
+	if c.h.Break == nil || c.h.Restore == nil {
+		t.Skip("harness cannot break the destination")
+	}
+	el := c.logger(t)
+	defer c.shutdown(t, el)
+	before := c.marker("failure", 0)
+	el.Info(before)
+	c.within(t, "Flush", func() { el.Flush() })
+	c.wait(t, []string{before})
+
+	c.h.Break(t)
+	for i := 1; i <= 5; i++ {
+		c.within(t, "logging to a broken destination", func() {
+			el.Error(c.marker("failure", i))
+		})
+	}
+	c.within(t, "Flush of a broken destination", func() { el.Flush() })
+	c.h.Restore(t)
+
+	// a handler may spend records finding out the destination is back, and
+	// a network one delivers those it keeps some time later
+	deadline := time.Now().Add(c.h.Timeout)
+	var markers []string
+	for i := 100; ; i++ {
+		m := c.marker("failure", i)
+		markers = append(markers, m)
+		c.within(t, "logging after Restore", func() {
+			el.Info(m)
+			el.Flush()
+		})
+		data := c.h.Received(t)
+		for _, m := range markers {
+			if bytes.Contains(data, []byte(m)) {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("no record received within %v after Restore", c.h.Timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TCPServer is a destination for network handlers such as
+// elog.EasyTCPHandler: it collects what its clients send. Stop and Start
+// make it unavailable and available again on the same address, for the
+// Break and Restore of a Harness.
+type TCPServer struct {
+	mutex    sync.Mutex
+	addr     string
+	listener net.Listener
+	conns    []net.Conn
+	buffer   bytes.Buffer
+}
+
+// NewTCPServer listens on a free loopback port.
+func NewTCPServer() (*TCPServer, error) {
+	s := &TCPServer{addr: "127.0.0.1:0"}
+	if err := s.Start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *TCPServer) Addr() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.addr
+}
+
+// Start listens again after Stop.
+func (s *TCPServer) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.addr = listener.Addr().String()
+	go s.accept(listener)
+	return nil
+}
+
+func (s *TCPServer) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+		go s.read(conn)
+	}
+}
+
+func (s *TCPServer) read(conn net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf)
+		s.mutex.Lock()
+		s.buffer.Write(buf[:n])
+		s.mutex.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Stop closes the listener and the connections; what was received is
+// kept.
+func (s *TCPServer) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *TCPServer) Close() error {
+	s.Stop()
+	return nil
+}
+
+func (s *TCPServer) Bytes() []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]byte(nil), s.buffer.Bytes()...)
+}
+
+func (s *TCPServer) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.buffer.Reset()
+}
@@ -0,0 +1,79 @@
+package elogtest
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/starjiang/elog"
+)
+
+func TestFileHandlerConformance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "elogtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var current string
+	RunHandlerConformance(t, Harness{
+		New: func(t *testing.T) io.Writer {
+			current, err = ioutil.TempDir(dir, "run")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return elog.NewEasyFileHandler(current, elog.LOG_MAX_BUFFER_SIZE)
+		},
+		Received: func(t *testing.T) []byte {
+			files, _ := filepath.Glob(filepath.Join(current, "*.log"))
+			var data []byte
+			for _, file := range files {
+				content, err := ioutil.ReadFile(file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data = append(data, content...)
+			}
+			return data
+		},
+		Timeout: 5 * time.Second,
+	})
+}
+
+func TestTCPHandlerConformance(t *testing.T) {
+	server, err := NewTCPServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	RunHandlerConformance(t, Harness{
+		New: func(t *testing.T) io.Writer {
+			server.Reset()
+			handler, err := elog.NewEasyTCPHandler(elog.TCPConfig{
+				Endpoints:    []string{server.Addr()},
+				DialTimeout:  time.Second,
+				WriteTimeout: time.Second,
+				RetryBackoff: 10 * time.Millisecond,
+				MaxBackoff:   50 * time.Millisecond,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return handler
+		},
+		Received: func(t *testing.T) []byte {
+			return server.Bytes()
+		},
+		Break: func(t *testing.T) {
+			server.Stop()
+		},
+		Restore: func(t *testing.T) {
+			if err := server.Start(); err != nil {
+				t.Fatal(err)
+			}
+		},
+		Timeout: 5 * time.Second,
+	})
+}
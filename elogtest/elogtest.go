@@ -0,0 +1,105 @@
+// Package elogtest helps testing log output: it replaces the parts of
+// records that change from run to run so the output can be compared with
+// golden files, and checks handlers with RunHandlerConformance.
+package elogtest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// LOG_ENV_UPDATE_GOLDEN set to 1 makes Golden rewrite the golden files
+// instead of comparing them.
+const LOG_ENV_UPDATE_GOLDEN = "ELOG_UPDATE_GOLDEN"
+
+var replacements = []struct {
+	re   *regexp.Regexp
+	repl []byte
+}{
+	// text header time, [2006-01-02 15:04:05]
+	{regexp.MustCompile(`\[\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\]`), []byte("[TIME]")},
+	// JSON and RFC 3339 times
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`), []byte("TIME")},
+	// text caller, [file:main.go line:12]
+	{regexp.MustCompile(`line:\d+\]`), []byte("line:N]")},
+	// JSON caller, "caller":{"file":"main.go","line":12}, and schema 1 "caller":"main.go:12"
+	{regexp.MustCompile(`("caller":\{[^}]*"line":)\d+`), []byte(`${1}N`)},
+	{regexp.MustCompile(`("caller":"[^"]*):\d+"`), []byte(`$1:N"`)},
+	// goroutine dumps
+	{regexp.MustCompile(`goroutine \d+`), []byte("goroutine N")},
+	{regexp.MustCompile(`\.go:\d+`), []byte(".go:N")},
+	{regexp.MustCompile(`\+0x[0-9a-f]+`), []byte("+0xX")},
+	{regexp.MustCompile(`\b0x[0-9a-f]{6,}\b`), []byte("0xADDR")},
+}
+
+// Normalize replaces timestamps, caller line numbers, goroutine ids and
+// stack offsets and addresses in log output by fixed placeholders.
+func Normalize(data []byte) []byte {
+	for _, r := range replacements {
+		data = r.re.ReplaceAll(data, r.repl)
+	}
+	return data
+}
+
+func NormalizeString(s string) string {
+	return string(Normalize([]byte(s)))
+}
+
+// Golden compares the normalized output with the golden file at path. With
+// ELOG_UPDATE_GOLDEN=1 the file is written instead.
+func Golden(t testing.TB, path string, output []byte) {
+	t.Helper()
+	got := Normalize(output)
+	if os.Getenv(LOG_ENV_UPDATE_GOLDEN) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with %s=1 to create it)", err, LOG_ENV_UPDATE_GOLDEN)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("log output differs from %s\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+// Buffer is a handler collecting output in memory, safe for concurrent use.
+type Buffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *Buffer) Write(data []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(data)
+}
+
+func (b *Buffer) Flush() {
+}
+
+func (b *Buffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]byte(nil), b.buffer.Bytes()...)
+}
+
+func (b *Buffer) String() string {
+	return string(b.Bytes())
+}
+
+func (b *Buffer) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.buffer.Reset()
+}
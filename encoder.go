@@ -0,0 +1,194 @@
+package elog
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	LOG_JSON_SCHEMA_VERSION = 2
+)
+
+// Encoder serializes a record to a handler. Encoders write each record
+// with a single Write, so that records written concurrently to the same
+// destination do not interleave.
+type Encoder interface {
+	Encode(w io.Writer, r *Record) error
+}
+
+// WithEncoder sets the encoder used for the handler and stderr output,
+// TextEncoder by default.
+func WithEncoder(enc Encoder) Option {
+	return func(el *EasyLogger) {
+		el.updateConfig(func(c *loggerConfig) {
+			c.encoder = enc
+		})
+	}
+}
+
+// SetEncoder replaces the encoder at runtime.
+func (el *EasyLogger) SetEncoder(enc Encoder) {
+	el.updateConfig(func(c *loggerConfig) {
+		c.encoder = enc
+	})
+}
+
+// TextEncoder writes the classic elog line:
+// [LEVEL][name][time][file:f line:n] message key=value...
+// Continuation lines of a multi-line message are indented with a tab so they
+// cannot be taken for records of their own; SingleLine escapes the line
+// breaks as \r and \n instead. LineEnding terminates records, "\n" if empty.
+type TextEncoder struct {
+	LineEnding string
+	SingleLine bool
+}
+
+func (te TextEncoder) Encode(w io.Writer, r *Record) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	b := append(*buf, levelToken(r.Level)...)
+	if r.Name != "" {
+		b = append(b, '[')
+		b = append(b, r.Name...)
+		b = append(b, ']')
+	}
+	b = append(b, timeToken(r.Time)...)
+	b = append(b, callerText(r.File, r.Line)...)
+	b = append(b, te.message(r.Message)...)
+	for _, k := range sortedKeys(r.Fields) {
+		b = append(b, ' ')
+		b = append(b, k...)
+		b = append(b, '=')
+		b = appendFieldValue(b, r.Fields[k])
+	}
+	b = append(b, lineEnding(te.LineEnding)...)
+	*buf = b
+	_, err := w.Write(b)
+	return err
+}
+
+var singleLineReplacer = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+
+func (te TextEncoder) message(msg string) string {
+	if !strings.ContainsAny(msg, "\r\n") {
+		return msg
+	}
+	if te.SingleLine {
+		return singleLineReplacer.Replace(msg)
+	}
+	return strings.Replace(msg, "\n", "\n\t", -1)
+}
+
+func lineEnding(ending string) string {
+	if ending == "" {
+		return "\n"
+	}
+	return ending
+}
+
+func formatFieldValue(v interface{}) string {
+	return string(appendFieldValue(nil, v))
+}
+
+// JSONEncoder writes one JSON object per line:
+// {"schema_version":2,"time":...,"level":...,"logger":...,
+// "caller":{"file":...,"line":...,"function":...,"package":...},"msg":...,"fields":{...}}
+// schema_version is bumped whenever this layout changes, see MigrateJSON.
+// Records never span lines; LineEnding terminates them, "\n" if empty.
+type JSONEncoder struct {
+	LineEnding string
+	// The caller object has file, line, function and package keys, each
+	// can be left out.
+	OmitCallerFile     bool
+	OmitCallerLine     bool
+	OmitCallerFunction bool
+	OmitCallerPackage  bool
+}
+
+func (je JSONEncoder) Encode(w io.Writer, r *Record) error {
+	buf := getJSONBuffer()
+	defer putJSONBuffer(buf)
+	buf.WriteString(`{"schema_version":`)
+	buf.WriteString(strconv.Itoa(LOG_JSON_SCHEMA_VERSION))
+	buf.WriteString(`,"time":`)
+	writeJSONString(buf, r.Time.Format("2006-01-02T15:04:05.000Z07:00"))
+	buf.WriteString(`,"level":`)
+	writeJSONString(buf, getLogLevelString(r.Level))
+	if r.Name != "" {
+		buf.WriteString(`,"logger":`)
+		writeJSONString(buf, r.Name)
+	}
+	je.writeCaller(buf, r)
+	buf.WriteString(`,"msg":`)
+	writeJSONString(buf, r.Message)
+	if len(r.Fields) > 0 {
+		buf.WriteString(`,"fields":`)
+		writeJSONObject(buf, r.Fields)
+	}
+	buf.WriteByte('}')
+	buf.WriteString(lineEnding(je.LineEnding))
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func (je JSONEncoder) writeCaller(buf *bytes.Buffer, r *Record) {
+	buf.WriteString(`,"caller":{`)
+	sep := ""
+	if !je.OmitCallerFile {
+		buf.WriteString(`"file":`)
+		writeJSONString(buf, r.File)
+		sep = ","
+	}
+	if !je.OmitCallerLine {
+		buf.WriteString(sep + `"line":`)
+		buf.WriteString(strconv.Itoa(r.Line))
+		sep = ","
+	}
+	pkg, function := callerFunction(r.PC)
+	if !je.OmitCallerFunction && function != "" {
+		buf.WriteString(sep + `"function":`)
+		writeJSONString(buf, function)
+		sep = ","
+	}
+	if !je.OmitCallerPackage && pkg != "" {
+		buf.WriteString(sep + `"package":`)
+		writeJSONString(buf, pkg)
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSONObject(buf *bytes.Buffer, m map[string]interface{}) {
+	buf.WriteByte('{')
+	for i, k := range sortedKeys(m) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(buf, k)
+		buf.WriteByte(':')
+		writeJSONValue(buf, m[k])
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	writeJSONValue(buf, s)
+}
+
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	b := getBuffer()
+	*b = appendJSONValue(*b, v)
+	buf.Write(*b)
+	putBuffer(b)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,174 @@
+package elog
+
+import (
+	"context"
+	"time"
+)
+
+// Entry is a logger bound to per-record settings such as a name or an
+// explicit timestamp. Entries are immutable and safe for concurrent use.
+type Entry struct {
+	logger *EasyLogger
+	name   string
+	time   time.Time
+	fields Fields
+	ctx    context.Context
+}
+
+// Named returns a child logger whose records carry name in the header,
+// e.g. "[INFO][scheduler]...".
+func (el *EasyLogger) Named(name string) *Entry {
+	return &Entry{logger: el, name: name}
+}
+
+func Named(name string) *Entry {
+	return logger.Named(name)
+}
+
+// Named appends name to the entry's name, joined by a dot.
+func (e *Entry) Named(name string) *Entry {
+	ne := *e
+	if ne.name == "" {
+		ne.name = name
+	} else {
+		ne.name = ne.name + "." + name
+	}
+	return &ne
+}
+
+func (el *EasyLogger) WithTime(t time.Time) *Entry {
+	return &Entry{logger: el, time: t}
+}
+
+// WithTime returns an Entry of the global logger stamping records with t
+// instead of the current time.
+func WithTime(t time.Time) *Entry {
+	return logger.WithTime(t)
+}
+
+func (el *EasyLogger) WithField(key string, value interface{}) *Entry {
+	return &Entry{logger: el, fields: Fields{key: value}}
+}
+
+func (el *EasyLogger) WithFields(fields Fields) *Entry {
+	return &Entry{logger: el, fields: fields.clone()}
+}
+
+func WithField(key string, value interface{}) *Entry {
+	return logger.WithField(key, value)
+}
+
+func WithFields(fields Fields) *Entry {
+	return logger.WithFields(fields)
+}
+
+func (e *Entry) WithField(key string, value interface{}) *Entry {
+	return e.WithFields(Fields{key: value})
+}
+
+func (e *Entry) WithFields(fields Fields) *Entry {
+	ne := *e
+	ne.fields = make(Fields, len(e.fields)+len(fields))
+	for k, v := range e.fields {
+		ne.fields[k] = v
+	}
+	for k, v := range fields {
+		ne.fields[k] = v
+	}
+	return &ne
+}
+
+// WithContext attaches ctx to the records of the entry, e.g. to carry the
+// tenant for WithTenancy.
+func (el *EasyLogger) WithContext(ctx context.Context) *Entry {
+	return &Entry{logger: el, ctx: ctx}
+}
+
+func WithContext(ctx context.Context) *Entry {
+	return logger.WithContext(ctx)
+}
+
+func (e *Entry) WithContext(ctx context.Context) *Entry {
+	ne := *e
+	ne.ctx = ctx
+	return &ne
+}
+
+func (e *Entry) WithTime(t time.Time) *Entry {
+	ne := *e
+	ne.time = t
+	return &ne
+}
+
+func (e *Entry) now() time.Time {
+	if e.time.IsZero() {
+		return e.logger.clock.Now()
+	}
+	return e.time
+}
+
+func (e *Entry) output(level int, args ...interface{}) {
+	if !e.logger.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(LOG_DEPTH_HANDLER)
+	if !e.logger.callerEnabled(level, pc) {
+		return
+	}
+	e.logger.record(&Record{Level: level, Name: e.name, Time: e.now(), File: file, Line: line, PC: pc, Message: sprintln(args...), Fields: e.fields.clone(), Context: e.ctx})
+}
+
+func (e *Entry) outputf(level int, format string, args ...interface{}) {
+	if !e.logger.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(LOG_DEPTH_HANDLER)
+	if !e.logger.callerEnabled(level, pc) {
+		return
+	}
+	e.logger.record(&Record{Level: level, Name: e.name, Time: e.now(), File: file, Line: line, PC: pc, Message: e.logger.sprintf(file, line, format, args...), Fields: e.fields.clone(), Context: e.ctx})
+}
+
+func (e *Entry) Info(args ...interface{}) {
+	e.output(LOG_LEVEL_INFO, args...)
+}
+func (e *Entry) Infof(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_INFO, format, args...)
+}
+
+func (e *Entry) Warn(args ...interface{}) {
+	e.output(LOG_LEVEL_WARN, args...)
+}
+func (e *Entry) Warnf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_WARN, format, args...)
+}
+
+func (e *Entry) Error(args ...interface{}) {
+	e.output(LOG_LEVEL_ERROR, args...)
+}
+func (e *Entry) Errorf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_ERROR, format, args...)
+}
+
+func (e *Entry) Fatal(args ...interface{}) {
+	e.outputValue(LOG_LEVEL_FATAL, args...)
+	e.logger.exit(LOG_EXIT_CODE_FATAL)
+}
+func (e *Entry) Fatalf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_FATAL, format, args...)
+	e.logger.exit(LOG_EXIT_CODE_FATAL)
+}
+
+func (e *Entry) Log(levelName string, args ...interface{}) {
+	e.output(getLogLevelInt(levelName), args...)
+}
+func (e *Entry) Logf(levelName string, format string, args ...interface{}) {
+	e.outputf(getLogLevelInt(levelName), format, args...)
+}
+
+func (e *Entry) Println(args ...interface{}) {
+	e.output(LOG_LEVEL_INFO, args...)
+}
+func (e *Entry) Printf(format string, args ...interface{}) {
+	e.outputf(LOG_LEVEL_INFO, format, args...)
+}
@@ -0,0 +1,138 @@
+package elog
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	LOG_ENV_LEVEL      = "ELOG_LEVEL"
+	LOG_ENV_FORMAT     = "ELOG_FORMAT"
+	LOG_ENV_PATH       = "ELOG_PATH"
+	LOG_ENV_TO_STDERR  = "ELOG_TO_STDERR"
+	LOG_ENV_FLUSH_TIME = "ELOG_FLUSH_TIME"
+)
+
+// Environ returns the logger configuration as "NAME=value" environment
+// entries: level, format (text, json or dev), file path, logToStderr and
+// flush time. LoadEnv applies them in the child process.
+func (el *EasyLogger) Environ() []string {
+	config := el.getConfig()
+	env := []string{
+		LOG_ENV_LEVEL + "=" + getLogLevelString(config.level),
+		LOG_ENV_TO_STDERR + "=" + strconv.FormatBool(config.logToStderr),
+	}
+	switch config.encoder.(type) {
+	case TextEncoder:
+		env = append(env, LOG_ENV_FORMAT+"=text")
+	case JSONEncoder:
+		env = append(env, LOG_ENV_FORMAT+"=json")
+	case DevEncoder:
+		env = append(env, LOG_ENV_FORMAT+"=dev")
+	}
+	el.lock()
+	defer el.unlock()
+	if efh, ok := el.writer.(*EasyFileHandler); ok {
+		env = append(env, LOG_ENV_PATH+"="+efh.path)
+	}
+	env = append(env, LOG_ENV_FLUSH_TIME+"="+strconv.Itoa(el.flushTime))
+	return env
+}
+
+// LoadEnv applies the ELOG_* environment variables set by a parent process.
+// Unset variables leave the configuration alone; call it before flag.Parse
+// to let command line flags take precedence.
+func (el *EasyLogger) LoadEnv() error {
+	if level, ok := os.LookupEnv(LOG_ENV_LEVEL); ok {
+		if _, known := currentLevels().values[level]; !known {
+			return envError(LOG_ENV_LEVEL, level)
+		}
+		el.setLevel(level, LOG_SOURCE_ENV)
+	}
+	if value, ok := os.LookupEnv(LOG_ENV_TO_STDERR); ok {
+		logToStderr, err := strconv.ParseBool(value)
+		if err != nil {
+			return envError(LOG_ENV_TO_STDERR, value)
+		}
+		el.setLogToStderr(logToStderr, LOG_SOURCE_ENV)
+	}
+	if format, ok := os.LookupEnv(LOG_ENV_FORMAT); ok {
+		switch format {
+		case "text":
+			el.SetEncoder(TextEncoder{})
+		case "json":
+			el.SetEncoder(JSONEncoder{})
+		case "dev":
+			el.SetEncoder(DevEncoder{Color: colorTerminal()})
+		default:
+			return envError(LOG_ENV_FORMAT, format)
+		}
+	}
+	if path, ok := os.LookupEnv(LOG_ENV_PATH); ok {
+		el.lock()
+		if efh, ok := el.writer.(*EasyFileHandler); ok {
+			efh.SetPath(path)
+		}
+		el.unlock()
+	}
+	if value, ok := os.LookupEnv(LOG_ENV_FLUSH_TIME); ok {
+		flushTime, err := strconv.Atoi(value)
+		if err != nil || flushTime <= 0 {
+			return envError(LOG_ENV_FLUSH_TIME, value)
+		}
+		el.lock()
+		el.flushTime = flushTime
+		el.unlock()
+		el.startFlushDaemon()
+	}
+	return nil
+}
+
+func envError(name, value string) error {
+	return errors.New("elog: bad " + name + " value " + strconv.Quote(value))
+}
+
+// Command is exec.Command with the logger configuration added to the
+// environment of the child.
+func (el *EasyLogger) Command(name string, arg ...string) *exec.Cmd {
+	cmd := exec.Command(name, arg...)
+	cmd.Env = append(withoutLogEnv(os.Environ()), el.Environ()...)
+	return cmd
+}
+
+func withoutLogEnv(env []string) []string {
+	kept := env[:0:0]
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, "ELOG_") {
+			kept = append(kept, kv)
+		}
+	}
+	return kept
+}
+
+// SetPath moves the handler to another directory, the next record opens
+// the file there.
+func (efh *EasyFileHandler) SetPath(path string) {
+	if path == efh.path {
+		return
+	}
+	efh.closeFile()
+	efh.path = path
+	efh.currentDate = ""
+	efh.nbytes = 0
+}
+
+func Environ() []string {
+	return logger.Environ()
+}
+
+func LoadEnv() error {
+	return logger.LoadEnv()
+}
+
+func Command(name string, arg ...string) *exec.Cmd {
+	return logger.Command(name, arg...)
+}
@@ -0,0 +1,128 @@
+package elog
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	LOG_EVENT_DROP     = 0
+	LOG_EVENT_ANNOTATE = 1
+)
+
+// EventValidator checks the fields of an event before it is logged.
+type EventValidator func(fields Fields) error
+
+// EventSchema is a basic EventValidator: fields that must be present and the
+// kind their values must have.
+type EventSchema struct {
+	Required []string
+	Types    map[string]reflect.Kind
+}
+
+func (es EventSchema) Validate(fields Fields) error {
+	var problems []string
+	for _, key := range es.Required {
+		if _, ok := fields[key]; !ok {
+			problems = append(problems, "missing "+key)
+		}
+	}
+	for key, kind := range es.Types {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if value == nil || reflect.TypeOf(value).Kind() != kind {
+			problems = append(problems, key+" is not "+kind.String())
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.New(strings.Join(problems, ", "))
+}
+
+// RegisterEvent sets the validator of the events called name.
+func (el *EasyLogger) RegisterEvent(name string, validator EventValidator) {
+	el.lock()
+	defer el.unlock()
+	old, _ := el.events.Load().(map[string]EventValidator)
+	events := make(map[string]EventValidator, len(old)+1)
+	for k, v := range old {
+		events[k] = v
+	}
+	events[name] = validator
+	el.events.Store(events)
+}
+
+// WithEventPolicy selects what happens to an invalid event outside
+// development mode: LOG_EVENT_DROP (default) discards it, LOG_EVENT_ANNOTATE
+// logs it with the problem in the event_error field. In development mode
+// (WithDevelopment) invalid events are logged after a WARN naming the problem.
+func WithEventPolicy(policy int) Option {
+	return func(el *EasyLogger) {
+		el.updateConfig(func(c *loggerConfig) {
+			c.eventPolicy = policy
+		})
+	}
+}
+
+// Event logs a business event at INFO level, the name being the message.
+// Events registered with RegisterEvent are validated first.
+func (el *EasyLogger) Event(name string, fields Fields) {
+	el.event(el.depth, &Record{Message: name, Fields: fields.clone()})
+}
+
+func (e *Entry) Event(name string, fields Fields) {
+	merged := e.fields.clone()
+	for k, v := range fields {
+		if merged == nil {
+			merged = Fields{}
+		}
+		merged[k] = v
+	}
+	e.logger.event(LOG_DEPTH_HANDLER, &Record{Name: e.name, Time: e.time, Message: name, Fields: merged, Context: e.ctx})
+}
+
+func (el *EasyLogger) event(depth int, r *Record) {
+	if !el.enabled(LOG_LEVEL_INFO) {
+		return
+	}
+	r.Level = LOG_LEVEL_INFO
+	r.File, r.Line, r.PC = getCaller(depth)
+	if !el.callerEnabled(LOG_LEVEL_INFO, r.PC) {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = el.clock.Now()
+	}
+	events, _ := el.events.Load().(map[string]EventValidator)
+	if validator, ok := events[r.Message]; ok {
+		if err := validator(r.Fields); err != nil {
+			config := el.getConfig()
+			if config.development {
+				el.record(&Record{Level: LOG_LEVEL_WARN, Name: r.Name, Time: r.Time, File: r.File, Line: r.Line,
+					Message: "elog: invalid event " + r.Message + ": " + err.Error(), Context: r.Context})
+			} else if config.eventPolicy == LOG_EVENT_ANNOTATE {
+				if r.Fields == nil {
+					r.Fields = Fields{}
+				}
+				r.Fields["event_error"] = err.Error()
+			} else {
+				return
+			}
+		}
+	}
+	el.record(r)
+}
+
+func RegisterEvent(name string, validator EventValidator) {
+	logger.RegisterEvent(name, validator)
+}
+
+func Event(name string, fields Fields) {
+	logger.Event(name, fields)
+}
@@ -0,0 +1,214 @@
+package elog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	LOG_MAX_POOLED_BUFFER = 64 * 1024
+)
+
+// Encoders assemble records in pooled buffers with the append functions
+// below; fmt and encoding/json are only used for types without a fast path.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+func getBuffer() *[]byte {
+	b := bufferPool.Get().(*[]byte)
+	*b = (*b)[:0]
+	return b
+}
+
+func putBuffer(b *[]byte) {
+	if cap(*b) <= LOG_MAX_POOLED_BUFFER {
+		bufferPool.Put(b)
+	}
+}
+
+var jsonBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+func getJSONBuffer() *bytes.Buffer {
+	buf := jsonBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putJSONBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= LOG_MAX_POOLED_BUFFER {
+		jsonBufferPool.Put(buf)
+	}
+}
+
+// appendFieldValue appends v as the text encoder shows it: fmt.Sprint, Go
+// quoted when empty or containing spaces, quotes or '='.
+func appendFieldValue(dst []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case string:
+		return appendTextString(dst, t)
+	case int:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int64:
+		return strconv.AppendInt(dst, t, 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int16:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int8:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case uint64:
+		return strconv.AppendUint(dst, t, 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case uint16:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case uint8:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case float64:
+		return strconv.AppendFloat(dst, t, 'g', -1, 64)
+	case float32:
+		return strconv.AppendFloat(dst, float64(t), 'g', -1, 32)
+	case bool:
+		return strconv.AppendBool(dst, t)
+	case time.Duration:
+		return append(dst, t.String()...)
+	}
+	if r, ok := resolveValue(v); ok {
+		return appendFieldValue(dst, r)
+	}
+	if s, ok := marshalText(v); ok {
+		return appendTextString(dst, s)
+	}
+	if flattened(v) {
+		return appendTextString(dst, nestedText(v))
+	}
+	return appendTextString(dst, fmt.Sprint(v))
+}
+
+func appendTextString(dst []byte, s string) []byte {
+	if s == "" || needsTextQuote(s) {
+		return strconv.AppendQuote(dst, s)
+	}
+	return append(dst, s...)
+}
+
+func needsTextQuote(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\r', '\n', '"', '=':
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONValue appends v the way encoding/json does without HTML
+// escaping, errors being encoded as their message.
+func appendJSONValue(dst []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case string:
+		return appendJSONString(dst, t)
+	case int:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int64:
+		return strconv.AppendInt(dst, t, 10)
+	case int32:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case uint:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case uint64:
+		return strconv.AppendUint(dst, t, 10)
+	case uint32:
+		return strconv.AppendUint(dst, uint64(t), 10)
+	case bool:
+		return strconv.AppendBool(dst, t)
+	case nil:
+		return append(dst, "null"...)
+	case json.Number:
+		return append(dst, t...)
+	}
+	if r, ok := resolveValue(v); ok {
+		return appendJSONValue(dst, r)
+	}
+	if err, ok := v.(error); ok {
+		if _, ok := v.(json.Marshaler); !ok {
+			return appendJSONString(dst, err.Error())
+		}
+	}
+	if isNested(v) {
+		return appendNestedJSON(dst, v)
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return appendJSONString(dst, fmt.Sprint(v))
+	}
+	return append(dst, bytes.TrimRight(out.Bytes(), "\n")...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString quotes s like encoding/json with SetEscapeHTML(false).
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
@@ -0,0 +1,61 @@
+package elog
+
+// Fields are key/value pairs attached to a record.
+type Fields map[string]interface{}
+
+func (f Fields) clone() Fields {
+	if len(f) == 0 {
+		return nil
+	}
+	nf := make(Fields, len(f))
+	for k, v := range f {
+		nf[k] = v
+	}
+	return nf
+}
+
+// FieldResolver computes a field value at log time.
+type FieldResolver func() interface{}
+
+type fieldResolver struct {
+	key   string
+	level int
+	fn    FieldResolver
+}
+
+// AddFieldResolver registers fn to be evaluated for every record at level or
+// above, adding its result under key. Fields set explicitly on the record
+// take precedence. Resolvers run outside the logger lock, so they may log.
+func (el *EasyLogger) AddFieldResolver(key string, level int, fn FieldResolver) {
+	el.lock()
+	defer el.unlock()
+	el.addFieldResolver(fieldResolver{key: key, level: level, fn: fn})
+}
+
+// addFieldResolver is called with the mutex held, or from an Option.
+func (el *EasyLogger) addFieldResolver(fr fieldResolver) {
+	old, _ := el.resolvers.Load().([]fieldResolver)
+	resolvers := make([]fieldResolver, len(old), len(old)+1)
+	copy(resolvers, old)
+	el.resolvers.Store(append(resolvers, fr))
+}
+
+func AddFieldResolver(key string, level int, fn FieldResolver) {
+	logger.AddFieldResolver(key, level, fn)
+}
+
+func (el *EasyLogger) resolveFields(r *Record) {
+	resolvers, _ := el.resolvers.Load().([]fieldResolver)
+	for _, fr := range resolvers {
+		if r.Level < fr.level {
+			continue
+		}
+		if _, ok := r.Fields[fr.key]; ok {
+			continue
+		}
+		if r.Fields == nil {
+			r.Fields = Fields{}
+		}
+		r.Fields[fr.key] = fr.fn()
+	}
+}
@@ -0,0 +1,88 @@
+package elog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// LOG_FILE_HEADER_PREFIX starts the header line of a log file; readers
+	// skip it like any line that is not a record.
+	LOG_FILE_HEADER_PREFIX = "# elog "
+)
+
+var processStart = time.Now()
+
+// FileHeader describes the process that wrote a log file, so a rotated
+// file is self-describing wherever it ends up.
+type FileHeader struct {
+	App           string    `json:"app"`
+	Version       string    `json:"version,omitempty"`
+	Host          string    `json:"host"`
+	PID           int       `json:"pid"`
+	Started       time.Time `json:"started"`
+	Opened        time.Time `json:"opened"`
+	Format        string    `json:"format"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+}
+
+// EnableHeader starts every new file with a header line, "# elog " and a
+// JSON FileHeader, stamped with the application version. Files that
+// already hold records are appended to without one.
+func (efh *EasyFileHandler) EnableHeader(version string) {
+	efh.header = true
+	efh.version = version
+}
+
+// writeHeader writes the header of a new file, the format being told by
+// its first record. It is called after rotateFile opened the file.
+func (efh *EasyFileHandler) writeHeader(first []byte) {
+	efh.headerPending = false
+	header := FileHeader{
+		App:     getAppName(),
+		Version: efh.version,
+		PID:     os.Getpid(),
+		Started: processStart,
+		Opened:  efh.clock.Now(),
+		Format:  "text",
+	}
+	header.Host, _ = os.Hostname()
+	if len(first) > 0 && first[0] == '{' {
+		header.Format = "json"
+		header.SchemaVersion = LOG_JSON_SCHEMA_VERSION
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return
+	}
+	line := LOG_FILE_HEADER_PREFIX + string(data) + "\n"
+	efh.buffer.WriteString(line)
+	efh.nbytes += int64(len(line))
+	efh.offset += int64(len(line))
+	efh.stats.setSize(efh.offset)
+}
+
+// ReadFileHeader returns the header of the log file at path.
+func ReadFileHeader(path string) (*FileHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if !strings.HasPrefix(line, LOG_FILE_HEADER_PREFIX) {
+		if err == nil {
+			err = errors.New("elog: no header in " + path)
+		}
+		return nil, err
+	}
+	header := &FileHeader{}
+	if err := json.Unmarshal([]byte(line[len(LOG_FILE_HEADER_PREFIX):]), header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
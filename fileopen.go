@@ -0,0 +1,111 @@
+package elog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SetOwner changes the owner and group of every file the handler creates
+// or opens, e.g. when the process runs as root before dropping privileges.
+// Pass -1 to keep the uid or gid.
+func (efh *EasyFileHandler) SetOwner(uid, gid int) {
+	efh.owner = &fileOwner{uid: uid, gid: gid}
+}
+
+// SetSymlinkCheck verifies log files when they are opened: a symlink is
+// refused unless it resolves inside allowedDir (any symlink is refused when
+// allowedDir is empty), and the file must be a regular file, so a planted
+// link cannot redirect the log on a shared host.
+func (efh *EasyFileHandler) SetSymlinkCheck(allowedDir string) {
+	efh.symlinkCheck = true
+	efh.symlinkDir = allowedDir
+}
+
+type fileOwner struct {
+	uid int
+	gid int
+}
+
+func (efh *EasyFileHandler) openFile(path string) (*os.File, error) {
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if efh.symlinkCheck {
+		if err := checkSymlink(path, efh.symlinkDir); err != nil {
+			return nil, err
+		}
+		if efh.symlinkDir == "" {
+			flags |= openNoFollow
+		}
+	}
+	file, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if efh.symlinkCheck {
+		info, err := file.Stat()
+		if err == nil && !info.Mode().IsRegular() {
+			err = errors.New("elog: " + path + " is not a regular file")
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if efh.owner != nil {
+		if err := file.Chown(efh.owner.uid, efh.owner.gid); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return file, nil
+}
+
+func checkSymlink(path string, allowedDir string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+	if allowedDir == "" {
+		return errors.New("elog: " + path + " is a symlink")
+	}
+	target, err := resolveLink(path)
+	if err != nil {
+		return err
+	}
+	dir, err := filepath.EvalSymlinks(allowedDir)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+		return errors.New("elog: " + path + " links to " + target + " outside " + allowedDir)
+	}
+	return nil
+}
+
+// resolveLink resolves path like filepath.EvalSymlinks, also when the link
+// points to a file that does not exist yet.
+func resolveLink(path string) (string, error) {
+	target, err := filepath.EvalSymlinks(path)
+	if !os.IsNotExist(err) {
+		return target, err
+	}
+	link, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(path), link)
+	}
+	dir, err := filepath.EvalSymlinks(filepath.Dir(link))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.Base(link)), nil
+}
@@ -0,0 +1,6 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package elog
+
+const openNoFollow = 0
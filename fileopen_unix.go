@@ -0,0 +1,8 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package elog
+
+import "syscall"
+
+const openNoFollow = syscall.O_NOFOLLOW
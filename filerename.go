@@ -0,0 +1,29 @@
+package elog
+
+import (
+	"os"
+	"runtime"
+	"time"
+)
+
+const (
+	LOG_RENAME_RETRIES = 5
+	LOG_RENAME_BACKOFF = 10 * time.Millisecond
+)
+
+// retryFileOp runs op, a rename or removal of a closed log file. On
+// Windows, where that fails while another process such as a log shipper or
+// virus scanner has the file open, it is retried with a doubling backoff.
+func retryFileOp(op func() error) error {
+	err := op()
+	if runtime.GOOS != "windows" {
+		return err
+	}
+	backoff := LOG_RENAME_BACKOFF
+	for i := 0; i < LOG_RENAME_RETRIES && err != nil && !os.IsNotExist(err); i++ {
+		time.Sleep(backoff)
+		backoff *= 2
+		err = op()
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package elog
+
+import (
+	"bufio"
+	"sync"
+)
+
+// FileStats reports the activity of an EasyFileHandler.
+type FileStats struct {
+	Path       string
+	BufferSize int
+	Adaptive   bool
+	Written    int64 // bytes
+	Size       int64 // bytes in the current file, buffered ones included
+	Flushes    int64 // Flush calls writing data
+	Overflows  int64 // writes that did not fit the buffer and forced a flush
+	Resizes    int64
+}
+
+type fileStats struct {
+	mutex sync.Mutex
+	FileStats
+	minSize   int
+	maxSize   int
+	overflows int   // since the last Flush
+	pending   int64 // bytes since the last Flush
+}
+
+// SetAdaptiveBuffer lets the buffer size follow the observed throughput
+// between min and max: it doubles when writes overflow the buffer between
+// two flushes and halves when a flush interval fills less than a quarter
+// of it. The current size is reported by Stats.
+func (efh *EasyFileHandler) SetAdaptiveBuffer(min, max int) {
+	if min <= 0 {
+		min = 4096
+	}
+	if max < min {
+		max = min
+	}
+	efh.stats.mutex.Lock()
+	efh.stats.Adaptive = true
+	efh.stats.minSize = min
+	efh.stats.maxSize = max
+	efh.stats.mutex.Unlock()
+	size := efh.bufferSize
+	if size < min {
+		size = min
+	} else if size > max {
+		size = max
+	}
+	efh.resizeBuffer(size)
+}
+
+func (efh *EasyFileHandler) Stats() FileStats {
+	path := efh.Checkpoint().Path
+	efh.stats.mutex.Lock()
+	defer efh.stats.mutex.Unlock()
+	stats := efh.stats.FileStats
+	stats.Path = path
+	stats.BufferSize = efh.bufferSize
+	return stats
+}
+
+// countWrite accounts data about to be written to the buffer.
+func (efh *EasyFileHandler) countWrite(n int) {
+	s := &efh.stats
+	s.mutex.Lock()
+	s.Written += int64(n)
+	s.Size += int64(n)
+	s.pending += int64(n)
+	if efh.buffer != nil && n > efh.buffer.Available() {
+		s.Overflows++
+		s.overflows++
+	}
+	s.mutex.Unlock()
+}
+
+func (s *fileStats) setSize(size int64) {
+	s.mutex.Lock()
+	s.Size = size
+	s.mutex.Unlock()
+}
+
+// tuneBuffer runs on Flush, after the buffer was written out.
+func (efh *EasyFileHandler) tuneBuffer() {
+	s := &efh.stats
+	s.mutex.Lock()
+	if s.pending > 0 {
+		s.Flushes++
+	}
+	size := efh.bufferSize
+	if s.Adaptive {
+		if s.overflows > 0 && size < s.maxSize {
+			size *= 2
+		} else if s.overflows == 0 && s.pending < int64(size/4) && size > s.minSize {
+			size /= 2
+		}
+		if size > s.maxSize {
+			size = s.maxSize
+		} else if size < s.minSize {
+			size = s.minSize
+		}
+	}
+	s.overflows = 0
+	s.pending = 0
+	s.mutex.Unlock()
+	if size != efh.bufferSize {
+		efh.resizeBuffer(size)
+	}
+}
+
+func (efh *EasyFileHandler) resizeBuffer(size int) {
+	if size == efh.bufferSize {
+		return
+	}
+	efh.stats.mutex.Lock()
+	efh.bufferSize = size
+	efh.stats.Resizes++
+	efh.stats.mutex.Unlock()
+	if efh.file != nil {
+		efh.buffer.Flush()
+		efh.buffer = bufio.NewWriterSize(efh.file, size)
+	}
+}
+
+// Describe returns the current file, or the file pattern before the first
+// record.
+func (efh *EasyFileHandler) Describe() string {
+	if path := efh.Checkpoint().Path; path != "" {
+		return path
+	}
+	return efh.fileName("{date}")
+}
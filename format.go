@@ -0,0 +1,157 @@
+package elog
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WithFormatCheck validates Printf-style calls at runtime. A call whose verbs
+// do not match its arguments produces a WARN record naming the problem and
+// the record is written as the raw format followed by its arguments, instead
+// of fmt's "%!d(string=...)" output. Meant for development builds.
+func WithFormatCheck() Option {
+	return func(el *EasyLogger) {
+		el.updateConfig(func(c *loggerConfig) {
+			c.formatCheck = true
+		})
+	}
+}
+
+// Outputf logs at level with a Printf-style format. calldepth counts the
+// frames to skip for the file and line number, 1 being the caller of
+// Outputf. Wrappers built on Outputf are recognized by go vet's printf check
+// as long as they pass format and args through unchanged.
+func (el *EasyLogger) Outputf(calldepth int, level int, format string, args ...interface{}) {
+	if !el.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(calldepth + 1)
+	if !el.callerEnabled(level, pc) {
+		return
+	}
+	el.record(&Record{Level: level, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: el.sprintf(file, line, format, args...)})
+}
+
+func (el *EasyLogger) sprintf(file string, line int, format string, args ...interface{}) string {
+	if el.getConfig().formatCheck {
+		if problem := checkFormat(format, args); problem != "" {
+			el.record(&Record{Level: LOG_LEVEL_WARN, Time: el.clock.Now(), File: file, Line: line, Message: "elog: bad format " + strconv.Quote(format) + ": " + problem})
+			if len(args) == 0 {
+				return format
+			}
+			return format + " " + sprintln(args...)
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+func checkFormat(format string, args []interface{}) string {
+	argNum := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		if i < len(format) && format[i] == '[' {
+			// explicit argument indexes are left to go vet
+			return ""
+		}
+		for _, precision := range []bool{false, true} {
+			if precision {
+				if i >= len(format) || format[i] != '.' {
+					break
+				}
+				i++
+			}
+			if i < len(format) && format[i] == '*' {
+				if argNum >= len(args) {
+					return "missing argument for *"
+				}
+				if !isInteger(args[argNum]) {
+					return "non-int argument for *"
+				}
+				argNum++
+				i++
+				continue
+			}
+			for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+		}
+		if i >= len(format) {
+			return "missing verb at end of format"
+		}
+		verb := format[i]
+		if verb == '%' {
+			continue
+		}
+		if argNum >= len(args) {
+			return "missing argument for %" + string(verb)
+		}
+		if !verbAccepts(verb, args[argNum]) {
+			return "%" + string(verb) + " has argument of wrong type " + fmt.Sprintf("%T", args[argNum])
+		}
+		argNum++
+	}
+	if argNum < len(args) {
+		return strconv.Itoa(len(args)-argNum) + " extra argument(s)"
+	}
+	return ""
+}
+
+func isInteger(arg interface{}) bool {
+	switch reflect.ValueOf(arg).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func verbAccepts(verb byte, arg interface{}) bool {
+	switch arg.(type) {
+	case fmt.Formatter:
+		return true
+	case error, fmt.Stringer:
+		if strings.IndexByte("vsqxXT", verb) >= 0 {
+			return true
+		}
+	}
+	if arg == nil {
+		return verb == 'v' || verb == 'T'
+	}
+	kind := reflect.ValueOf(arg).Kind()
+	switch kind {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr, reflect.Interface:
+		// fmt applies the verb to the elements
+		return verb != 't' || kind == reflect.Ptr
+	}
+	switch verb {
+	case 'v', 'T':
+		return true
+	case 't':
+		return kind == reflect.Bool
+	case 'd', 'o', 'O', 'c', 'U':
+		return isInteger(arg)
+	case 'b':
+		return isInteger(arg) || isFloat(kind)
+	case 'x', 'X':
+		return isInteger(arg) || isFloat(kind) || kind == reflect.String
+	case 's', 'q':
+		return kind == reflect.String || (verb == 'q' && isInteger(arg))
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		return isFloat(kind)
+	case 'p':
+		return kind == reflect.Chan || kind == reflect.Func || kind == reflect.UnsafePointer
+	}
+	return false
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64 || kind == reflect.Complex64 || kind == reflect.Complex128
+}
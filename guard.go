@@ -0,0 +1,222 @@
+package elog
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GuardConfig configures StartResourceGuard.
+type GuardConfig struct {
+	// Interval between two readings of the cgroup, 5s by default.
+	Interval time.Duration
+	// MemoryHigh is the fraction of the cgroup memory limit above which
+	// logging degrades, 0.9 by default.
+	MemoryHigh float64
+	// PressureHigh is the memory or I/O pressure (PSI some avg10, in
+	// percent) above which logging degrades, 20 by default.
+	PressureHigh float64
+	// Level is the minimum level while degraded, WARN by default.
+	Level string
+	// SampleEvery keeps one in SampleEvery records below ERROR while
+	// degraded, 10 by default.
+	SampleEvery int
+	// CgroupPath is the cgroup directory, found from /proc/self/cgroup by
+	// default.
+	CgroupPath string
+}
+
+type cgroupReading struct {
+	memory   float64 // usage / limit, 0 without limit
+	pressure float64 // highest of memory and io some avg10
+}
+
+// StartResourceGuard watches the memory usage and the memory and I/O
+// pressure of the process's cgroup and degrades logging while the container
+// is near its limits: the level is raised and records below ERROR are
+// sampled. Logging is restored once usage is back under the thresholds.
+func (el *EasyLogger) StartResourceGuard(config GuardConfig) (stop func(), err error) {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+	if config.MemoryHigh <= 0 {
+		config.MemoryHigh = 0.9
+	}
+	if config.PressureHigh <= 0 {
+		config.PressureHigh = 20
+	}
+	if config.Level == "" {
+		config.Level = "WARN"
+	}
+	if config.SampleEvery <= 0 {
+		config.SampleEvery = 10
+	}
+	if config.CgroupPath == "" {
+		if config.CgroupPath, err = findCgroup(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := readCgroup(config.CgroupPath); err != nil {
+		return nil, err
+	}
+	ticker := el.clock.NewTicker(config.Interval)
+	quit := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer ticker.Stop()
+		degraded, saved := false, 0
+		level := getLogLevelInt(config.Level)
+		for {
+			select {
+			case <-ticker.C():
+			case <-quit:
+				if degraded {
+					el.restore(level, saved)
+				}
+				return
+			case <-el.done:
+				return
+			}
+			reading, err := readCgroup(config.CgroupPath)
+			if err != nil {
+				continue
+			}
+			high := reading.memory >= config.MemoryHigh || reading.pressure >= config.PressureHigh
+			low := reading.memory < config.MemoryHigh-0.05 && reading.pressure < config.PressureHigh/2
+			if !degraded && high {
+				degraded = true
+				el.updateConfigFrom(LOG_SOURCE_GUARD, func(c *loggerConfig) {
+					saved = c.level
+					if c.level < level {
+						c.level = level
+					}
+					c.sample = config.SampleEvery
+				})
+				selfLogf(LOG_LEVEL_WARN, "resource guard: memory %.0f%%, pressure %.1f, logging at %s with 1 in %d records kept",
+					reading.memory*100, reading.pressure, config.Level, config.SampleEvery)
+			} else if degraded && low {
+				degraded = false
+				el.restore(level, saved)
+				selfLogf(LOG_LEVEL_INFO, "resource guard: memory %.0f%%, pressure %.1f, logging restored", reading.memory*100, reading.pressure)
+			}
+		}
+	}()
+	return func() {
+		once.Do(func() { close(quit) })
+	}, nil
+}
+
+func StartResourceGuard(config GuardConfig) (stop func(), err error) {
+	return logger.StartResourceGuard(config)
+}
+
+// restore undoes the degradation, unless the level was changed meanwhile.
+func (el *EasyLogger) restore(level, saved int) {
+	el.updateConfigFrom(LOG_SOURCE_GUARD, func(c *loggerConfig) {
+		if c.level == level || c.level == saved {
+			c.level = saved
+		}
+		c.sample = 0
+	})
+}
+
+// sampledOut reports whether a degraded logger drops a record at level.
+func (el *EasyLogger) sampledOut(config *loggerConfig, level int) bool {
+	return config.sample > 1 && level < LOG_LEVEL_ERROR && atomic.AddUint64(&el.sampled, 1)%uint64(config.sample) != 0
+}
+
+// findCgroup returns the cgroup v2 directory of the process, or its v1
+// memory controller directory.
+func findCgroup() (string, error) {
+	file, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		var root string
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			root = "/sys/fs/cgroup"
+		case strings.Contains(","+parts[1]+",", ",memory,"):
+			root = "/sys/fs/cgroup/memory"
+		default:
+			continue
+		}
+		// Inside a container the cgroup namespace usually makes the
+		// process's own cgroup the root of the mount.
+		if dir := root + parts[2]; fileIsExist(dir) {
+			return dir, nil
+		}
+		return root, nil
+	}
+	return "", errors.New("elog: no cgroup found")
+}
+
+func readCgroup(dir string) (cgroupReading, error) {
+	var reading cgroupReading
+	if usage, err := readCgroupInt(dir + "/memory.current"); err == nil {
+		if limit, err := readCgroupInt(dir + "/memory.max"); err == nil && limit > 0 {
+			reading.memory = float64(usage) / float64(limit)
+		}
+		reading.pressure = readPressure(dir + "/memory.pressure")
+		if io := readPressure(dir + "/io.pressure"); io > reading.pressure {
+			reading.pressure = io
+		}
+		return reading, nil
+	}
+	usage, err := readCgroupInt(dir + "/memory.usage_in_bytes")
+	if err != nil {
+		return reading, err
+	}
+	// cgroup v1 reports no limit as a huge number.
+	if limit, err := readCgroupInt(dir + "/memory.limit_in_bytes"); err == nil && limit > 0 && limit < 1<<62 {
+		reading.memory = float64(usage) / float64(limit)
+	}
+	return reading, nil
+}
+
+// readCgroupInt reads a number file, "max" yields 0.
+func readCgroupInt(path string) (int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readPressure returns the "some avg10" value of a PSI file, 0 when
+// missing.
+func readPressure(path string) float64 {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "some ") {
+			continue
+		}
+		for _, kv := range strings.Fields(line)[1:] {
+			if strings.HasPrefix(kv, "avg10=") {
+				avg, _ := strconv.ParseFloat(kv[len("avg10="):], 64)
+				return avg
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,275 @@
+package elog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ClickHouseConfig configures EasyClickHouseHandler, records are inserted
+// over the HTTP interface with INSERT ... FORMAT JSONEachRow.
+type ClickHouseConfig struct {
+	URL      string // http://127.0.0.1:8123
+	Database string
+	Table    string
+	User     string
+	Password string
+	// Columns maps record parts to columns, DefaultSQLColumns when zero.
+	// Fields is stored as a JSON string.
+	Columns SQLColumns
+	// FieldColumns maps field keys to their own columns, e.g.
+	// {"user_id": "user_id"}; mapped fields are left out of Columns.Fields.
+	FieldColumns map[string]string
+	BatchSize    int
+	Timeout      time.Duration
+	Client       *http.Client
+	// Compression names the codec of the request bodies, e.g. "gzip",
+	// see RegisterCodec. Empty sends them uncompressed.
+	Compression string
+	// Transport configures TLS, HTTP authentication and how requests are
+	// sent; only authentication applies when Client is set.
+	Transport TransportConfig
+}
+
+// EasyClickHouseHandler batches records and inserts them with one HTTP
+// request per batch.
+type EasyClickHouseHandler struct {
+	config   ClickHouseConfig
+	endpoint string
+	client   *http.Client
+	codec    Codec
+	mutex    sync.Mutex
+	buffer   bytes.Buffer
+	body     bytes.Buffer
+	rows     int
+	written  int64
+	failed   int64
+	rawBytes int64
+	sent     int64
+	lastErr  error
+}
+
+func NewEasyClickHouseHandler(config ClickHouseConfig) (*EasyClickHouseHandler, error) {
+	table := config.Table
+	if config.Database != "" {
+		table = config.Database + "." + table
+	}
+	if !isSQLIdentifier(table) {
+		return nil, errors.New("elog: bad clickhouse table name " + strconv.Quote(table))
+	}
+	if config.Columns == (SQLColumns{}) {
+		config.Columns = DefaultSQLColumns
+	}
+	for _, column := range config.FieldColumns {
+		if !isSQLIdentifier(column) {
+			return nil, errors.New("elog: bad clickhouse column name " + strconv.Quote(column))
+		}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	codec, err := LookupCodec(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("query", "INSERT INTO "+table+" FORMAT JSONEachRow")
+	u.RawQuery = q.Encode()
+	client := config.Client
+	if client == nil {
+		transport, err := config.Transport.roundTripper(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		client = &http.Client{Timeout: config.Timeout, Transport: transport}
+	}
+	return &EasyClickHouseHandler{config: config, endpoint: u.String(), client: client, codec: codec}, nil
+}
+
+func (ech *EasyClickHouseHandler) Write(data []byte) (int, error) {
+	r := &Record{Level: LOG_LEVEL_INFO, Time: time.Now(), Message: string(trimNewline(data))}
+	return ech.WriteRecord(r, data)
+}
+
+func (ech *EasyClickHouseHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	ech.mutex.Lock()
+	defer ech.mutex.Unlock()
+	ech.encodeRow(r)
+	ech.rows++
+	if ech.rows >= ech.config.BatchSize {
+		if err := ech.insert(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (ech *EasyClickHouseHandler) encodeRow(r *Record) {
+	buf := &ech.buffer
+	c := ech.config.Columns
+	sep := byte('{')
+	column := func(name string) {
+		buf.WriteByte(sep)
+		sep = ','
+		writeJSONString(buf, name)
+		buf.WriteByte(':')
+	}
+	if c.Time != "" {
+		column(c.Time)
+		// DateTime64(3) accepts this layout in JSONEachRow
+		writeJSONString(buf, r.Time.UTC().Format("2006-01-02 15:04:05.000"))
+	}
+	if c.Level != "" {
+		column(c.Level)
+		writeJSONString(buf, getLogLevelString(r.Level))
+	}
+	if c.Logger != "" {
+		column(c.Logger)
+		writeJSONString(buf, r.Name)
+	}
+	if c.Caller != "" {
+		column(c.Caller)
+		caller := ""
+		if r.File != "" {
+			caller = r.File + ":" + strconv.Itoa(r.Line)
+		}
+		writeJSONString(buf, caller)
+	}
+	if c.Message != "" {
+		column(c.Message)
+		writeJSONString(buf, r.Message)
+	}
+	rest := r.Fields
+	if len(ech.config.FieldColumns) > 0 {
+		rest = Fields{}
+		for k, v := range r.Fields {
+			if name, ok := ech.config.FieldColumns[k]; ok {
+				column(name)
+				writeJSONValue(buf, v)
+			} else {
+				rest[k] = v
+			}
+		}
+	}
+	if c.Fields != "" {
+		column(c.Fields)
+		var fields bytes.Buffer
+		writeJSONObject(&fields, rest)
+		writeJSONString(buf, fields.String())
+	}
+	if sep == '{' {
+		buf.WriteByte('{')
+	}
+	buf.WriteString("}\n")
+}
+
+// insert posts the pending batch. A failed batch is dropped and counted.
+func (ech *EasyClickHouseHandler) insert() error {
+	if ech.rows == 0 {
+		return nil
+	}
+	rows := ech.rows
+	err := ech.post(ech.buffer.Bytes())
+	ech.buffer.Reset()
+	ech.rows = 0
+	if err != nil {
+		ech.failed += int64(rows)
+		ech.lastErr = err
+		return err
+	}
+	ech.written += int64(rows)
+	return nil
+}
+
+func (ech *EasyClickHouseHandler) post(body []byte) error {
+	ech.rawBytes += int64(len(body))
+	if ech.codec != nil {
+		ech.body.Reset()
+		if err := ech.codec.Compress(&ech.body, body); err != nil {
+			return err
+		}
+		body = ech.body.Bytes()
+	}
+	ech.sent += int64(len(body))
+	req, err := http.NewRequest("POST", ech.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if ech.codec != nil {
+		req.Header.Set("Content-Encoding", ech.codec.Name())
+	}
+	if ech.config.User != "" {
+		req.Header.Set("X-ClickHouse-User", ech.config.User)
+		req.Header.Set("X-ClickHouse-Key", ech.config.Password)
+	}
+	ech.config.Transport.authorize(req)
+	resp, err := ech.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("elog: clickhouse " + resp.Status + ": " + string(bytes.TrimSpace(msg)))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+func (ech *EasyClickHouseHandler) Flush() {
+	ech.FlushErr()
+}
+
+// FlushErr inserts the pending rows and returns the error of a failed
+// insert.
+func (ech *EasyClickHouseHandler) FlushErr() error {
+	ech.mutex.Lock()
+	defer ech.mutex.Unlock()
+	return ech.insert()
+}
+
+func (ech *EasyClickHouseHandler) Close() error {
+	ech.mutex.Lock()
+	defer ech.mutex.Unlock()
+	return ech.insert()
+}
+
+// ClickHouseStats reports the insert counters of the handler.
+// RawBytes and SentBytes are the request bodies before and after
+// compression.
+type ClickHouseStats struct {
+	Written   int64
+	Failed    int64
+	Pending   int
+	RawBytes  int64
+	SentBytes int64
+	LastError error
+}
+
+func (ech *EasyClickHouseHandler) Stats() ClickHouseStats {
+	ech.mutex.Lock()
+	defer ech.mutex.Unlock()
+	return ClickHouseStats{Written: ech.written, Failed: ech.failed, Pending: ech.rows, RawBytes: ech.rawBytes, SentBytes: ech.sent, LastError: ech.lastErr}
+}
+
+func (ech *EasyClickHouseHandler) Describe() string {
+	u, err := url.Parse(ech.endpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + " " + u.Query().Get("query")
+}
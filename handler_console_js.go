@@ -0,0 +1,50 @@
+//go:build js && wasm
+// +build js,wasm
+
+package elog
+
+import (
+	"io"
+	"syscall/js"
+)
+
+// EasyConsoleHandler writes records to the browser or Node.js console,
+// with console.debug, info, warn or error depending on the level.
+type EasyConsoleHandler struct {
+	console js.Value
+}
+
+func NewEasyConsoleHandler() *EasyConsoleHandler {
+	return &EasyConsoleHandler{console: js.Global().Get("console")}
+}
+
+func (econ *EasyConsoleHandler) Write(data []byte) (int, error) {
+	econ.console.Call("log", string(trimNewline(data)))
+	return len(data), nil
+}
+
+func (econ *EasyConsoleHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	method := "error"
+	switch {
+	case r.Level < LOG_LEVEL_INFO:
+		method = "debug"
+	case r.Level < LOG_LEVEL_WARN:
+		method = "info"
+	case r.Level < LOG_LEVEL_ERROR:
+		method = "warn"
+	}
+	econ.console.Call(method, string(trimNewline(data)))
+	return len(data), nil
+}
+
+func (econ *EasyConsoleHandler) Describe() string {
+	return "console"
+}
+
+// The global logger writes to the console: there are no files and nobody
+// parses flags in a browser.
+func defaultHandler(path string) io.Writer {
+	return NewEasyConsoleHandler()
+}
+
+const requireFlagParse = false
@@ -0,0 +1,32 @@
+//go:build !js || !wasm
+// +build !js !wasm
+
+package elog
+
+import (
+	"io"
+	"os"
+)
+
+// EasyConsoleHandler writes records to stdout; in js/wasm builds it writes
+// to the browser or Node.js console instead, so shared code can use it on
+// both.
+type EasyConsoleHandler struct{}
+
+func NewEasyConsoleHandler() *EasyConsoleHandler {
+	return &EasyConsoleHandler{}
+}
+
+func (econ *EasyConsoleHandler) Write(data []byte) (int, error) {
+	return os.Stdout.Write(data)
+}
+
+func (econ *EasyConsoleHandler) Describe() string {
+	return "console"
+}
+
+func defaultHandler(path string) io.Writer {
+	return NewEasyFileHandler(path, LOG_MAX_BUFFER_SIZE)
+}
+
+const requireFlagParse = true
@@ -0,0 +1,115 @@
+package elog
+
+import (
+	"bytes"
+	"io"
+)
+
+// EasyDualHandler writes every record twice: once as human readable text
+// and once as JSON, each to its own handler with its own rotation. The
+// logger encoder is not used.
+type EasyDualHandler struct {
+	Text        io.Writer
+	JSON        io.Writer
+	TextEncoder Encoder
+	JSONEncoder Encoder
+	buffer      bytes.Buffer
+}
+
+func NewEasyDualHandler(text io.Writer, json io.Writer) *EasyDualHandler {
+	return &EasyDualHandler{Text: text, JSON: json, TextEncoder: TextEncoder{}, JSONEncoder: JSONEncoder{}}
+}
+
+// NewEasyDualFileHandler writes app-date.log (text) and app-date.json (JSON)
+// under path.
+func NewEasyDualFileHandler(path string, bufferSize int) *EasyDualHandler {
+	json := NewEasyFileHandler(path, bufferSize)
+	json.SetExtension(".json")
+	return NewEasyDualHandler(NewEasyFileHandler(path, bufferSize), json)
+}
+
+// Write passes unstructured data to the text handler only.
+func (edh *EasyDualHandler) Write(data []byte) (int, error) {
+	return edh.Text.Write(data)
+}
+
+func (edh *EasyDualHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	err := edh.encode(edh.Text, edh.TextEncoder, r)
+	if jerr := edh.encode(edh.JSON, edh.JSONEncoder, r); err == nil {
+		err = jerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (edh *EasyDualHandler) encode(w io.Writer, enc Encoder, r *Record) error {
+	edh.buffer.Reset()
+	if err := enc.Encode(&edh.buffer, r); err != nil {
+		return err
+	}
+	var err error
+	if rw, ok := w.(RecordWriter); ok {
+		_, err = rw.WriteRecord(r, edh.buffer.Bytes())
+	} else {
+		_, err = w.Write(edh.buffer.Bytes())
+	}
+	return err
+}
+
+func (edh *EasyDualHandler) Flush() {
+	edh.FlushErr()
+}
+
+func (edh *EasyDualHandler) FlushErr() error {
+	err := flushHandler(edh.Text)
+	if jerr := flushHandler(edh.JSON); err == nil {
+		err = jerr
+	}
+	return err
+}
+
+func (edh *EasyDualHandler) Close() error {
+	err := closeHandler(edh.Text)
+	if jerr := closeHandler(edh.JSON); err == nil {
+		err = jerr
+	}
+	return err
+}
+
+func (edh *EasyDualHandler) Rotate() error {
+	var err error
+	for _, w := range []io.Writer{edh.Text, edh.JSON} {
+		if rotator, ok := w.(Rotator); ok {
+			if rerr := rotator.Rotate(); err == nil {
+				err = rerr
+			}
+		}
+	}
+	return err
+}
+
+func (edh *EasyDualHandler) Sync() error {
+	var err error
+	for _, w := range []io.Writer{edh.Text, edh.JSON} {
+		if syncer, ok := w.(Syncer); ok {
+			if serr := syncer.Sync(); err == nil {
+				err = serr
+			}
+		}
+	}
+	return err
+}
+
+func (edh *EasyDualHandler) SetClock(c Clock) {
+	for _, w := range []io.Writer{edh.Text, edh.JSON} {
+		if cs, ok := w.(interface{ SetClock(Clock) }); ok {
+			cs.SetClock(c)
+		}
+	}
+}
+
+func (edh *EasyDualHandler) Describe() string {
+	return describeHandler(edh.Text) + ", " + describeHandler(edh.JSON)
+}
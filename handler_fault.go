@@ -0,0 +1,147 @@
+package elog
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is returned by EasyFaultHandler for the writes it fails.
+var ErrInjectedFault = errors.New("elog: injected fault")
+
+// FaultConfig sets the failures EasyFaultHandler injects. Rates are
+// probabilities between 0 and 1 drawn independently for every write.
+type FaultConfig struct {
+	// ErrorRate fails a write with ErrInjectedFault without passing it on.
+	ErrorRate float64
+	// PartialRate passes on only the first half of a write and fails it
+	// with io.ErrShortWrite.
+	PartialRate float64
+	// LatencyRate delays a write by Latency plus up to LatencyJitter.
+	LatencyRate   float64
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// Seed makes the failures reproducible; 0 seeds from the clock.
+	Seed int64
+}
+
+// EasyFaultHandler wraps a handler and injects failures, latency and partial
+// writes into it, to test how a service, and the failover and retry
+// wrappers of elog, behave when logging degrades.
+type EasyFaultHandler struct {
+	handler io.Writer
+	mutex   sync.Mutex
+	config  FaultConfig
+	rand    *rand.Rand
+	stats   FaultStats
+}
+
+// FaultStats counts the writes of EasyFaultHandler and the failures
+// injected into them.
+type FaultStats struct {
+	Writes  int64
+	Errors  int64
+	Partial int64
+	Delayed int64
+}
+
+func NewEasyFaultHandler(handler io.Writer, config FaultConfig) *EasyFaultHandler {
+	efi := &EasyFaultHandler{handler: handler}
+	efi.SetConfig(config)
+	return efi
+}
+
+// SetConfig replaces the failures injected from the next write on, e.g. to
+// degrade logging in the middle of a test.
+func (efi *EasyFaultHandler) SetConfig(config FaultConfig) {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	efi.mutex.Lock()
+	defer efi.mutex.Unlock()
+	efi.config = config
+	efi.rand = rand.New(rand.NewSource(seed))
+}
+
+type faultPlan struct {
+	delay   time.Duration
+	fail    bool
+	partial bool
+}
+
+func (efi *EasyFaultHandler) plan() faultPlan {
+	efi.mutex.Lock()
+	defer efi.mutex.Unlock()
+	var plan faultPlan
+	c := efi.config
+	efi.stats.Writes++
+	if c.LatencyRate > 0 && efi.rand.Float64() < c.LatencyRate {
+		plan.delay = c.Latency
+		if c.LatencyJitter > 0 {
+			plan.delay += time.Duration(efi.rand.Int63n(int64(c.LatencyJitter)))
+		}
+		efi.stats.Delayed++
+	}
+	if c.ErrorRate > 0 && efi.rand.Float64() < c.ErrorRate {
+		plan.fail = true
+		efi.stats.Errors++
+	} else if c.PartialRate > 0 && efi.rand.Float64() < c.PartialRate {
+		plan.partial = true
+		efi.stats.Partial++
+	}
+	return plan
+}
+
+func (efi *EasyFaultHandler) Write(data []byte) (int, error) {
+	return efi.write(nil, data)
+}
+
+func (efi *EasyFaultHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	return efi.write(r, data)
+}
+
+func (efi *EasyFaultHandler) write(r *Record, data []byte) (int, error) {
+	plan := efi.plan()
+	if plan.delay > 0 {
+		time.Sleep(plan.delay)
+	}
+	if plan.fail {
+		return 0, ErrInjectedFault
+	}
+	if plan.partial {
+		n, err := efi.handler.Write(data[:len(data)/2])
+		if err == nil {
+			err = io.ErrShortWrite
+		}
+		return n, err
+	}
+	if rw, ok := efi.handler.(RecordWriter); ok && r != nil {
+		return rw.WriteRecord(r, data)
+	}
+	return efi.handler.Write(data)
+}
+
+func (efi *EasyFaultHandler) Stats() FaultStats {
+	efi.mutex.Lock()
+	defer efi.mutex.Unlock()
+	return efi.stats
+}
+
+func (efi *EasyFaultHandler) Flush() {
+	efi.FlushErr()
+}
+
+func (efi *EasyFaultHandler) FlushErr() error {
+	return flushHandler(efi.handler)
+}
+
+func (efi *EasyFaultHandler) Close() error {
+	return closeHandler(efi.handler)
+}
+
+func (efi *EasyFaultHandler) Describe() string {
+	return "fault injection " + describeHandler(efi.handler)
+}
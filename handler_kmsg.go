@@ -0,0 +1,91 @@
+package elog
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	LOG_KMSG_MAX_RECORD_SIZE = 976
+	LOG_SYSLOG_FACILITY_USER = 1
+)
+
+// EasyKmsgHandler writes every record to the kernel ring buffer through
+// /dev/kmsg, prefixed with its syslog priority, for embedded Linux targets
+// without persistent storage. Records longer than MaxRecordSize bytes are
+// truncated, the kernel rejects longer lines.
+type EasyKmsgHandler struct {
+	Ident         string
+	MaxRecordSize int
+	file          *os.File
+}
+
+func NewEasyKmsgHandler(ident string) (*EasyKmsgHandler, error) {
+	return NewEasyKmsgHandlerPath("/dev/kmsg", ident)
+}
+
+// NewEasyKmsgHandlerPath is NewEasyKmsgHandler writing to path instead of
+// /dev/kmsg.
+func NewEasyKmsgHandlerPath(path string, ident string) (*EasyKmsgHandler, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &EasyKmsgHandler{Ident: ident, MaxRecordSize: LOG_KMSG_MAX_RECORD_SIZE, file: file}, nil
+}
+
+func (ekh *EasyKmsgHandler) Write(data []byte) (int, error) {
+	return ekh.write(LOG_SYSLOG_INFO, data)
+}
+
+func (ekh *EasyKmsgHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	return ekh.write(SyslogSeverity(r.Level), data)
+}
+
+func (ekh *EasyKmsgHandler) write(severity int, data []byte) (int, error) {
+	prefix := "<" + strconv.Itoa(LOG_SYSLOG_FACILITY_USER*8+severity) + ">"
+	if ekh.Ident != "" {
+		prefix += ekh.Ident + ": "
+	}
+	line := make([]byte, 0, len(prefix)+len(data))
+	line = append(line, prefix...)
+	line = append(line, trimNewline(data)...)
+	line = truncateRecord(line, ekh.MaxRecordSize)
+	if _, err := ekh.file.Write(line); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (ekh *EasyKmsgHandler) Close() error {
+	return ekh.file.Close()
+}
+
+func trimNewline(data []byte) []byte {
+	for len(data) > 0 && (data[len(data)-1] == '\n' || data[len(data)-1] == '\r') {
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+const truncatedMark = "...(truncated)"
+
+// truncateRecord cuts data to max bytes, ending it with a truncation mark
+// and never splitting a UTF-8 sequence. max <= 0 means no limit.
+func truncateRecord(data []byte, max int) []byte {
+	if max <= 0 || len(data) <= max {
+		return data
+	}
+	if max <= len(truncatedMark) {
+		return data[:max]
+	}
+	cut := max - len(truncatedMark)
+	for cut > 0 && data[cut]&0xc0 == 0x80 {
+		cut--
+	}
+	return append(data[:cut:cut], truncatedMark...)
+}
+
+func (ekh *EasyKmsgHandler) Describe() string {
+	return ekh.file.Name()
+}
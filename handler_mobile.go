@@ -0,0 +1,41 @@
+package elog
+
+// EasyMobileHandler writes records to the native log of the platform when
+// built with cgo for Android (liblog, under tag) or iOS (os_log, under
+// subsystem tag and category), so Go code embedded in an app through
+// gomobile shows up in logcat or Console with proper levels. Elsewhere it
+// writes to stderr.
+type EasyMobileHandler struct {
+	tag      string
+	category string
+	mobileLog
+}
+
+// NewEasyMobileHandler returns a handler logging under tag, e.g. the app
+// package name; category is used by os_log only.
+func NewEasyMobileHandler(tag, category string) *EasyMobileHandler {
+	emh := &EasyMobileHandler{tag: tag, category: category}
+	emh.open()
+	return emh
+}
+
+func (emh *EasyMobileHandler) Write(data []byte) (int, error) {
+	return emh.WriteRecord(&Record{Level: LOG_LEVEL_INFO}, data)
+}
+
+func (emh *EasyMobileHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	msg := trimNewline(data)
+	// logcat truncates entries at about 4KB.
+	for len(msg) > LOG_MOBILE_MAX_ENTRY {
+		emh.write(r.Level, msg[:LOG_MOBILE_MAX_ENTRY])
+		msg = msg[LOG_MOBILE_MAX_ENTRY:]
+	}
+	emh.write(r.Level, msg)
+	return len(data), nil
+}
+
+func (emh *EasyMobileHandler) Describe() string {
+	return mobileLogName + " " + emh.tag
+}
+
+const LOG_MOBILE_MAX_ENTRY = 4000
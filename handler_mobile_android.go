@@ -0,0 +1,40 @@
+//go:build android && cgo
+// +build android,cgo
+
+package elog
+
+/*
+#cgo LDFLAGS: -llog
+#include <stdlib.h>
+#include <android/log.h>
+*/
+import "C"
+
+import "unsafe"
+
+const mobileLogName = "logcat"
+
+type mobileLog struct {
+	ctag *C.char
+}
+
+func (emh *EasyMobileHandler) open() {
+	emh.ctag = C.CString(emh.tag)
+}
+
+func (emh *EasyMobileHandler) write(level int, msg []byte) {
+	prio := C.ANDROID_LOG_ERROR
+	switch {
+	case level < LOG_LEVEL_INFO:
+		prio = C.ANDROID_LOG_DEBUG
+	case level < LOG_LEVEL_WARN:
+		prio = C.ANDROID_LOG_INFO
+	case level < LOG_LEVEL_ERROR:
+		prio = C.ANDROID_LOG_WARN
+	case level >= LOG_LEVEL_FATAL:
+		prio = C.ANDROID_LOG_FATAL
+	}
+	cmsg := C.CString(string(msg))
+	C.__android_log_write(C.int(prio), emh.ctag, cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
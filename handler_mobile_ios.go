@@ -0,0 +1,46 @@
+//go:build ios && cgo
+// +build ios,cgo
+
+package elog
+
+/*
+#include <stdlib.h>
+#include <os/log.h>
+
+static void elog_os_log(os_log_t log, os_log_type_t type, const char *msg) {
+	os_log_with_type(log, type, "%{public}s", msg);
+}
+*/
+import "C"
+
+import "unsafe"
+
+const mobileLogName = "os_log"
+
+type mobileLog struct {
+	log C.os_log_t
+}
+
+func (emh *EasyMobileHandler) open() {
+	subsystem, category := C.CString(emh.tag), C.CString(emh.category)
+	emh.log = C.os_log_create(subsystem, category)
+	C.free(unsafe.Pointer(subsystem))
+	C.free(unsafe.Pointer(category))
+}
+
+func (emh *EasyMobileHandler) write(level int, msg []byte) {
+	kind := C.os_log_type_t(C.OS_LOG_TYPE_ERROR)
+	switch {
+	case level < LOG_LEVEL_INFO:
+		kind = C.OS_LOG_TYPE_DEBUG
+	case level < LOG_LEVEL_WARN:
+		kind = C.OS_LOG_TYPE_INFO
+	case level < LOG_LEVEL_ERROR:
+		kind = C.OS_LOG_TYPE_DEFAULT
+	case level >= LOG_LEVEL_FATAL:
+		kind = C.OS_LOG_TYPE_FAULT
+	}
+	cmsg := C.CString(string(msg))
+	C.elog_os_log(emh.log, kind, cmsg)
+	C.free(unsafe.Pointer(cmsg))
+}
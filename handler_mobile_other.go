@@ -0,0 +1,18 @@
+//go:build !(android && cgo) && !(ios && cgo)
+// +build !android !cgo
+// +build !ios !cgo
+
+package elog
+
+import "os"
+
+const mobileLogName = "stderr"
+
+type mobileLog struct{}
+
+func (emh *EasyMobileHandler) open() {
+}
+
+func (emh *EasyMobileHandler) write(level int, msg []byte) {
+	os.Stderr.Write(append(msg[:len(msg):len(msg)], '\n'))
+}
@@ -0,0 +1,492 @@
+package elog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NatsConfig configures EasyNatsHandler.
+type NatsConfig struct {
+	// URL of the server, nats://[user:password@]host:4222.
+	URL     string
+	Subject string
+	// Name identifies the connection on the server.
+	Name  string
+	Token string
+	// JetStream publishes with a reply inbox and tracks the stream acks
+	// asynchronously; Flush waits up to AckTimeout for outstanding acks.
+	JetStream  bool
+	AckTimeout time.Duration
+	// MaxPendingAcks bounds unacknowledged JetStream publishes, publishing
+	// blocks until acks arrive or time out once it is reached.
+	MaxPendingAcks int
+	DialTimeout    time.Duration
+	// Encoder for published records, JSONEncoder by default.
+	Encoder Encoder
+	// Transport configures TLS, started after the server INFO as the NATS
+	// protocol wants it; BearerToken, Username and Password are used when
+	// neither Token nor the URL carry credentials.
+	Transport TransportConfig
+	// Compression names the codec of the messages, e.g. "gzip", see
+	// RegisterCodec. Compressed messages carry a Content-Encoding header;
+	// a server without header support gets them uncompressed.
+	Compression string
+}
+
+// EasyNatsHandler publishes every record as a JSON message to a NATS
+// subject, optionally acknowledged by JetStream. It reconnects on the next
+// record after a connection failure.
+type EasyNatsHandler struct {
+	config    NatsConfig
+	mutex     sync.Mutex
+	conn      net.Conn
+	writer    *bufio.Writer
+	inbox     string
+	ackSeq    uint64
+	ackMutex  sync.Mutex
+	ackCond   *sync.Cond
+	pending   map[string]time.Time
+	published int64
+	acked     int64
+	failed    int64
+	// failed at the last FlushErr
+	reportedFailed int64
+	rawBytes       int64
+	sent           int64
+	lastErr        error
+	codec          Codec
+	headers        bool
+	payload        bytes.Buffer
+}
+
+func NewEasyNatsHandler(config NatsConfig) (*EasyNatsHandler, error) {
+	if config.Subject == "" {
+		return nil, errors.New("elog: nats subject required")
+	}
+	if config.Encoder == nil {
+		config.Encoder = JSONEncoder{}
+	}
+	if config.AckTimeout <= 0 {
+		config.AckTimeout = 5 * time.Second
+	}
+	if config.MaxPendingAcks <= 0 {
+		config.MaxPendingAcks = 4096
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	codec, err := LookupCodec(config.Compression)
+	if err != nil {
+		return nil, err
+	}
+	enh := &EasyNatsHandler{config: config, pending: map[string]time.Time{}, codec: codec}
+	enh.ackCond = sync.NewCond(&enh.ackMutex)
+	enh.inbox = "_INBOX." + strconv.FormatInt(rand.Int63(), 36) + strconv.FormatInt(time.Now().UnixNano(), 36)
+	enh.mutex.Lock()
+	err = enh.connect()
+	enh.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if enh.codec != nil && !enh.headers {
+		enh.warnUncompressed()
+	}
+	return enh, nil
+}
+
+func (enh *EasyNatsHandler) warnUncompressed() {
+	selfLogf(LOG_LEVEL_WARN, "%s: server does not support headers, publishing uncompressed", enh.Describe())
+}
+
+func (enh *EasyNatsHandler) connect() error {
+	u, err := url.Parse(enh.config.URL)
+	if err != nil {
+		return err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	conn, err := enh.config.Transport.dial(host, enh.config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(enh.config.DialTimeout))
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("elog: nats handshake failed: %q %v", line, err)
+	}
+	if enh.config.Transport.tlsEnabled() {
+		if conn, err = enh.config.Transport.dialTLS(conn, host, enh.config.DialTimeout); err != nil {
+			return err
+		}
+		reader = bufio.NewReader(conn)
+		conn.SetReadDeadline(time.Now().Add(enh.config.DialTimeout))
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	json.Unmarshal([]byte(strings.TrimSpace(line[len("INFO "):])), &info)
+	headers := enh.codec != nil && info.Headers
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"lang":     "go",
+		"version":  "elog",
+		"protocol": 1,
+		"name":     enh.config.Name,
+		"headers":  headers,
+	}
+	if enh.config.Transport.tlsEnabled() {
+		options["tls_required"] = true
+	}
+	if u.User != nil {
+		options["user"] = u.User.Username()
+		options["pass"], _ = u.User.Password()
+	} else if enh.config.Token == "" && enh.config.Transport.Username != "" {
+		options["user"] = enh.config.Transport.Username
+		options["pass"] = enh.config.Transport.Password
+	}
+	if enh.config.Token != "" {
+		options["auth_token"] = enh.config.Token
+	} else if u.User == nil && enh.config.Transport.BearerToken != "" {
+		options["auth_token"] = enh.config.Transport.BearerToken
+	}
+	connect, _ := json.Marshal(options)
+	writer := bufio.NewWriterSize(conn, 32*1024)
+	writer.WriteString("CONNECT " + string(connect) + "\r\n")
+	if enh.config.JetStream {
+		writer.WriteString("SUB " + enh.inbox + ".* 1\r\n")
+	}
+	writer.WriteString("PING\r\n")
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	for {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if strings.HasPrefix(line, "PONG") {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return errors.New("elog: nats " + strings.TrimSpace(line))
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+	enh.conn = conn
+	enh.writer = writer
+	enh.headers = headers
+	go enh.readLoop(conn, reader)
+	return nil
+}
+
+func (enh *EasyNatsHandler) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			enh.broken(conn, err)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case line == "PING":
+			enh.mutex.Lock()
+			if enh.conn == conn {
+				enh.writer.WriteString("PONG\r\n")
+				enh.writer.Flush()
+			}
+			enh.mutex.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			enh.setError(errors.New("elog: nats " + line))
+		case strings.HasPrefix(line, "MSG "):
+			args := strings.Fields(line)
+			size, err := strconv.Atoi(args[len(args)-1])
+			if err != nil || len(args) < 4 {
+				enh.broken(conn, errors.New("elog: nats bad MSG line"))
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				enh.broken(conn, err)
+				return
+			}
+			enh.ack(args[1], payload[:size])
+		}
+	}
+}
+
+func (enh *EasyNatsHandler) broken(conn net.Conn, err error) {
+	enh.mutex.Lock()
+	current := enh.conn == conn
+	if current {
+		conn.Close()
+		enh.conn = nil
+		enh.setError(err)
+	}
+	enh.mutex.Unlock()
+	if current {
+		selfLogf(LOG_LEVEL_WARN, "%s: connection lost: %v", enh.Describe(), err)
+	}
+}
+
+func (enh *EasyNatsHandler) setError(err error) {
+	enh.ackMutex.Lock()
+	enh.lastErr = err
+	enh.ackMutex.Unlock()
+}
+
+func (enh *EasyNatsHandler) ack(subject string, payload []byte) {
+	var reply struct {
+		Error *struct {
+			Description string `json:"description"`
+		} `json:"error"`
+	}
+	json.Unmarshal(payload, &reply)
+	enh.ackMutex.Lock()
+	defer enh.ackMutex.Unlock()
+	if _, ok := enh.pending[subject]; !ok {
+		return
+	}
+	delete(enh.pending, subject)
+	if reply.Error != nil {
+		enh.failed++
+		enh.lastErr = errors.New("elog: jetstream " + reply.Error.Description)
+	} else {
+		enh.acked++
+	}
+	enh.ackCond.Broadcast()
+}
+
+// expireAcks counts publishes unacknowledged for longer than AckTimeout as
+// failed. It is called with ackMutex held.
+func (enh *EasyNatsHandler) expireAcks() {
+	now := time.Now()
+	for subject, since := range enh.pending {
+		if now.Sub(since) >= enh.config.AckTimeout {
+			delete(enh.pending, subject)
+			enh.failed++
+			enh.lastErr = errors.New("elog: jetstream ack timeout")
+		}
+	}
+}
+
+func (enh *EasyNatsHandler) Write(data []byte) (int, error) {
+	if err := enh.send(trimNewline(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Encoder makes the logger encode the published records, sharing the
+// encoding with the other handlers using the same encoder.
+func (enh *EasyNatsHandler) Encoder() Encoder {
+	return enh.config.Encoder
+}
+
+func (enh *EasyNatsHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	if err := enh.send(trimNewline(data)); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// send publishes payload and reports reconnects and failures to the self
+// log once the mutex is released.
+func (enh *EasyNatsHandler) send(payload []byte) error {
+	enh.mutex.Lock()
+	reconnect := enh.conn == nil
+	err := enh.publish(payload)
+	uncompressed := enh.codec != nil && !enh.headers
+	enh.mutex.Unlock()
+	if err != nil {
+		selfLogf(LOG_LEVEL_WARN, "%s: %v", enh.Describe(), err)
+	} else if reconnect {
+		selfLogf(LOG_LEVEL_INFO, "%s: reconnected", enh.Describe())
+	}
+	if reconnect && err == nil && uncompressed {
+		enh.warnUncompressed()
+	}
+	return err
+}
+
+// publish sends payload, it is called with mutex held.
+func (enh *EasyNatsHandler) publish(payload []byte) error {
+	if enh.config.JetStream {
+		enh.waitPendingBelow(enh.config.MaxPendingAcks)
+	}
+	if enh.conn == nil {
+		if err := enh.connect(); err != nil {
+			enh.setError(err)
+			return err
+		}
+	}
+	reply := ""
+	if enh.config.JetStream {
+		enh.ackSeq++
+		reply = enh.inbox + "." + strconv.FormatUint(enh.ackSeq, 36)
+		enh.ackMutex.Lock()
+		enh.pending[reply] = time.Now()
+		enh.ackMutex.Unlock()
+		reply += " "
+	}
+	size := len(payload)
+	if enh.headers {
+		enh.payload.Reset()
+		if err := enh.codec.Compress(&enh.payload, payload); err != nil {
+			enh.setError(err)
+			return err
+		}
+		header := "NATS/1.0\r\nContent-Encoding: " + enh.codec.Name() + "\r\n\r\n"
+		size = len(header) + enh.payload.Len()
+		enh.writer.WriteString("HPUB " + enh.config.Subject + " " + reply + strconv.Itoa(len(header)) + " " + strconv.Itoa(size) + "\r\n")
+		enh.writer.WriteString(header)
+		enh.writer.Write(enh.payload.Bytes())
+	} else {
+		enh.writer.WriteString("PUB " + enh.config.Subject + " " + reply + strconv.Itoa(len(payload)) + "\r\n")
+		enh.writer.Write(payload)
+	}
+	_, err := enh.writer.WriteString("\r\n")
+	if err != nil {
+		enh.conn.Close()
+		enh.conn = nil
+		enh.setError(err)
+		return err
+	}
+	enh.ackMutex.Lock()
+	enh.published++
+	enh.rawBytes += int64(len(payload))
+	enh.sent += int64(size)
+	enh.ackMutex.Unlock()
+	return nil
+}
+
+// waitPendingBelow blocks until fewer than max acks are outstanding,
+// flushing the publishes they wait for. It is called with mutex held and
+// releases it while waiting, so the read loop can answer server PINGs.
+func (enh *EasyNatsHandler) waitPendingBelow(max int) {
+	enh.ackMutex.Lock()
+	full := len(enh.pending) >= max
+	enh.ackMutex.Unlock()
+	if !full {
+		return
+	}
+	if enh.conn != nil {
+		enh.writer.Flush()
+	}
+	enh.mutex.Unlock()
+	defer enh.mutex.Lock()
+	enh.ackMutex.Lock()
+	defer enh.ackMutex.Unlock()
+	deadline := time.Now().Add(enh.config.AckTimeout)
+	for len(enh.pending) >= max && time.Now().Before(deadline) {
+		enh.waitAck(deadline)
+	}
+	enh.expireAcks()
+}
+
+// waitAck waits on ackCond until an ack arrives or deadline passes. It is
+// called with ackMutex held.
+func (enh *EasyNatsHandler) waitAck(deadline time.Time) {
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		enh.ackMutex.Lock()
+		enh.ackCond.Broadcast()
+		enh.ackMutex.Unlock()
+	})
+	enh.ackCond.Wait()
+	timer.Stop()
+}
+
+// Flush sends buffered messages and, with JetStream, waits up to
+// AckTimeout for the outstanding acks.
+func (enh *EasyNatsHandler) Flush() {
+	enh.FlushErr()
+}
+
+// FlushErr flushes like Flush and returns the error of the buffered
+// messages not sent or, with JetStream, the last error of the messages
+// refused or not acked since the previous FlushErr.
+func (enh *EasyNatsHandler) FlushErr() error {
+	var err error
+	enh.mutex.Lock()
+	if enh.writer != nil && enh.conn != nil {
+		if err = enh.writer.Flush(); err != nil {
+			enh.setError(err)
+		}
+	}
+	enh.mutex.Unlock()
+	if !enh.config.JetStream {
+		return err
+	}
+	enh.ackMutex.Lock()
+	defer enh.ackMutex.Unlock()
+	deadline := time.Now().Add(enh.config.AckTimeout)
+	for len(enh.pending) > 0 && time.Now().Before(deadline) {
+		enh.waitAck(deadline)
+	}
+	enh.expireAcks()
+	if enh.failed > enh.reportedFailed {
+		enh.reportedFailed = enh.failed
+		if err == nil {
+			err = enh.lastErr
+		}
+	}
+	return err
+}
+
+func (enh *EasyNatsHandler) Close() error {
+	enh.Flush()
+	enh.mutex.Lock()
+	defer enh.mutex.Unlock()
+	if enh.conn == nil {
+		return nil
+	}
+	err := enh.conn.Close()
+	enh.conn = nil
+	return err
+}
+
+// NatsStats reports the publish and JetStream ack counters of the handler.
+// RawBytes and SentBytes are the message payloads before and after
+// compression.
+type NatsStats struct {
+	Published   int64
+	Acked       int64
+	Failed      int64
+	PendingAcks int
+	RawBytes    int64
+	SentBytes   int64
+	LastError   error
+}
+
+func (enh *EasyNatsHandler) Stats() NatsStats {
+	enh.ackMutex.Lock()
+	defer enh.ackMutex.Unlock()
+	return NatsStats{Published: enh.published, Acked: enh.acked, Failed: enh.failed, PendingAcks: len(enh.pending), RawBytes: enh.rawBytes, SentBytes: enh.sent, LastError: enh.lastErr}
+}
+
+func (enh *EasyNatsHandler) Describe() string {
+	u, err := url.Parse(enh.config.URL)
+	if err != nil {
+		return enh.config.Subject
+	}
+	u.User = nil
+	return u.String() + " " + enh.config.Subject
+}
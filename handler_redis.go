@@ -0,0 +1,344 @@
+package elog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RedisConfig configures EasyRedisHandler.
+type RedisConfig struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	Stream   string
+	// MaxLen trims the stream on every XADD, approximately (MAXLEN ~)
+	// unless ExactTrim is set; 0 disables trimming.
+	MaxLen    int64
+	ExactTrim bool
+	// BatchSize is the number of XADDs pipelined before their replies are
+	// read; Flush always drains the pipeline.
+	BatchSize   int
+	DialTimeout time.Duration
+	// ReadTimeout bounds sending the pipeline and reading its replies, so
+	// that a stalled server fails the batch instead of blocking every log
+	// call; DialTimeout when 0.
+	ReadTimeout time.Duration
+	// Transport configures TLS; its Username and Password are used when
+	// the fields above are empty.
+	Transport TransportConfig
+}
+
+// EasyRedisHandler appends every record to a Redis Stream with XADD, one
+// entry per record with the fields level, time, caller, logger, msg and
+// fields (JSON). Commands are pipelined and the connection is reopened on
+// the next record after a failure.
+type EasyRedisHandler struct {
+	config  RedisConfig
+	mutex   sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	writer  *bufio.Writer
+	pending int
+	written int64
+	failed  int64
+	lastErr error
+}
+
+func NewEasyRedisHandler(config RedisConfig) (*EasyRedisHandler, error) {
+	if config.Stream == "" {
+		return nil, errors.New("elog: redis stream required")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 128
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = config.DialTimeout
+	}
+	if config.Password == "" {
+		config.Username, config.Password = config.Transport.Username, config.Transport.Password
+	}
+	erh := &EasyRedisHandler{config: config}
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	if err := erh.connect(); err != nil {
+		return nil, err
+	}
+	return erh, nil
+}
+
+func (erh *EasyRedisHandler) connect() error {
+	conn, err := erh.config.Transport.dial(erh.config.Addr, erh.config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	if conn, err = erh.config.Transport.dialTLS(conn, erh.config.Addr, erh.config.DialTimeout); err != nil {
+		return err
+	}
+	erh.conn = conn
+	erh.reader = bufio.NewReader(conn)
+	erh.writer = bufio.NewWriterSize(conn, 64*1024)
+	erh.pending = 0
+	var handshake [][]string
+	if erh.config.Password != "" {
+		if erh.config.Username != "" {
+			handshake = append(handshake, []string{"AUTH", erh.config.Username, erh.config.Password})
+		} else {
+			handshake = append(handshake, []string{"AUTH", erh.config.Password})
+		}
+	}
+	if erh.config.DB != 0 {
+		handshake = append(handshake, []string{"SELECT", strconv.Itoa(erh.config.DB)})
+	}
+	for _, args := range handshake {
+		writeRESPCommand(erh.writer, args...)
+	}
+	conn.SetDeadline(time.Now().Add(erh.config.DialTimeout))
+	if err := erh.writer.Flush(); err != nil {
+		erh.disconnect(err)
+		return err
+	}
+	for range handshake {
+		if _, err := readRESPReply(erh.reader); err != nil {
+			erh.disconnect(err)
+			return err
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return nil
+}
+
+func (erh *EasyRedisHandler) disconnect(err error) {
+	if erh.conn != nil {
+		erh.conn.Close()
+		erh.conn = nil
+	}
+	erh.failed += int64(erh.pending)
+	erh.pending = 0
+	erh.lastErr = err
+}
+
+func (erh *EasyRedisHandler) Write(data []byte) (int, error) {
+	return erh.xadd([]string{"msg", string(trimNewline(data))}, len(data))
+}
+
+func (erh *EasyRedisHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	values := []string{
+		"level", getLogLevelString(r.Level),
+		"time", r.Time.Format(time.RFC3339Nano),
+		"caller", r.File + ":" + strconv.Itoa(r.Line),
+	}
+	if r.Name != "" {
+		values = append(values, "logger", r.Name)
+	}
+	values = append(values, "msg", r.Message)
+	if len(r.Fields) > 0 {
+		var buf bytes.Buffer
+		writeJSONObject(&buf, r.Fields)
+		values = append(values, "fields", buf.String())
+	}
+	return erh.xadd(values, len(data))
+}
+
+func (erh *EasyRedisHandler) xadd(values []string, n int) (written int, err error) {
+	reconnected := false
+	defer func() {
+		if err != nil {
+			selfLogf(LOG_LEVEL_WARN, "%s: %v", erh.Describe(), err)
+		} else if reconnected {
+			selfLogf(LOG_LEVEL_INFO, "%s: reconnected", erh.Describe())
+		}
+	}()
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	if erh.conn == nil {
+		if err := erh.connect(); err != nil {
+			erh.lastErr = err
+			erh.failed++
+			return 0, err
+		}
+		reconnected = true
+	}
+	args := []string{"XADD", erh.config.Stream}
+	if erh.config.MaxLen > 0 {
+		if erh.config.ExactTrim {
+			args = append(args, "MAXLEN", strconv.FormatInt(erh.config.MaxLen, 10))
+		} else {
+			args = append(args, "MAXLEN", "~", strconv.FormatInt(erh.config.MaxLen, 10))
+		}
+	}
+	args = append(args, "*")
+	args = append(args, values...)
+	// a full buffer is sent on the way
+	erh.conn.SetDeadline(time.Now().Add(erh.config.ReadTimeout))
+	if err := writeRESPCommand(erh.writer, args...); err != nil {
+		erh.disconnect(err)
+		erh.failed++
+		return 0, err
+	}
+	erh.pending++
+	if erh.pending >= erh.config.BatchSize {
+		if err := erh.drain(); err != nil {
+			return 0, err
+		}
+	}
+	erh.conn.SetDeadline(time.Time{})
+	return n, nil
+}
+
+// drain sends the pipelined commands and reads their replies.
+func (erh *EasyRedisHandler) drain() error {
+	if erh.conn == nil {
+		return nil
+	}
+	erh.conn.SetDeadline(time.Now().Add(erh.config.ReadTimeout))
+	if err := erh.writer.Flush(); err != nil {
+		erh.disconnect(err)
+		return err
+	}
+	for erh.pending > 0 {
+		_, err := readRESPReply(erh.reader)
+		if _, ok := err.(redisError); ok {
+			erh.pending--
+			erh.failed++
+			erh.lastErr = err
+			continue
+		}
+		if err != nil {
+			erh.disconnect(err)
+			return err
+		}
+		erh.pending--
+		erh.written++
+	}
+	erh.conn.SetDeadline(time.Time{})
+	return nil
+}
+
+func (erh *EasyRedisHandler) Flush() {
+	erh.FlushErr()
+}
+
+// FlushErr drains the pipeline and returns the error that broke the
+// connection.
+func (erh *EasyRedisHandler) FlushErr() error {
+	erh.mutex.Lock()
+	err := erh.drain()
+	erh.mutex.Unlock()
+	if err != nil {
+		selfLogf(LOG_LEVEL_WARN, "%s: %v", erh.Describe(), err)
+	}
+	return err
+}
+
+func (erh *EasyRedisHandler) Close() error {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	err := erh.drain()
+	if erh.conn != nil {
+		if cerr := erh.conn.Close(); err == nil {
+			err = cerr
+		}
+		erh.conn = nil
+	}
+	return err
+}
+
+// RedisStats reports the XADD counters of the handler.
+type RedisStats struct {
+	Written   int64
+	Failed    int64
+	Pending   int
+	LastError error
+}
+
+func (erh *EasyRedisHandler) Stats() RedisStats {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	return RedisStats{Written: erh.written, Failed: erh.failed, Pending: erh.pending, LastError: erh.lastErr}
+}
+
+type redisError string
+
+func (re redisError) Error() string {
+	return "elog: redis " + string(re)
+}
+
+// writeRESPCommand buffers a command, returning the error of sending the
+// buffer when it fills up.
+func writeRESPCommand(w *bufio.Writer, args ...string) error {
+	if _, err := w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n"); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		if _, err := w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n"); err != nil {
+			return err
+		}
+		if _, err := w.WriteString(arg); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRESPReply reads one reply. Server errors are returned as redisError
+// with the connection still usable.
+func readRESPReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, errors.New("elog: redis bad reply")
+	}
+	body := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readRESPReply(r)
+			if _, ok := err.(redisError); err != nil && !ok {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, errors.New("elog: redis bad reply")
+}
+
+func (erh *EasyRedisHandler) Describe() string {
+	return "redis://" + erh.config.Addr + "/" + strconv.Itoa(erh.config.DB) + " " + erh.config.Stream
+}
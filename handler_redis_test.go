@@ -0,0 +1,40 @@
+package elog
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestRedisHandlerWriteToClosedConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	erh, err := NewEasyRedisHandler(RedisConfig{Addr: listener.Addr().String(), Stream: "logs", BatchSize: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer erh.Close()
+	// records larger than the buffer are sent without waiting for a drain
+	data := []byte(strings.Repeat("x", 32*1024) + "\n")
+	for i := 0; i < 100; i++ {
+		if _, err := erh.Write(data); err != nil {
+			if stats := erh.Stats(); stats.Failed == 0 || stats.LastError == nil {
+				t.Fatalf("Write failed with %v but Stats reports %+v", err, stats)
+			}
+			return
+		}
+	}
+	t.Fatal("writes to a closed connection never failed")
+}
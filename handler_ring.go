@@ -0,0 +1,180 @@
+package elog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EasyRingHandler keeps the most recent records in memory, bounded by
+// MaxBytes of encoded output, dropping the oldest first. It replaces file
+// rotation on devices that cannot afford writing to flash: the ring can be
+// dumped on demand or on crash with WriteTo. Records longer than
+// MaxRecordSize are truncated. MaxRecords and MaxAge, when set, also bound
+// the ring by count and by the age of the records, so that it holds a
+// consistent recent window, e.g. the last 5 minutes, at any traffic rate.
+type EasyRingHandler struct {
+	MaxRecordSize int
+	MaxRecords    int
+	MaxAge        time.Duration
+	mutex         sync.Mutex
+	clock         Clock
+	maxBytes      int
+	size          int
+	records       []ringRecord
+	head          int
+}
+
+type ringRecord struct {
+	time  time.Time
+	added time.Time
+	level int
+	data  []byte
+}
+
+func NewEasyRingHandler(maxBytes int) *EasyRingHandler {
+	return &EasyRingHandler{maxBytes: maxBytes, MaxRecordSize: maxBytes, clock: systemClock{}}
+}
+
+func (erh *EasyRingHandler) SetClock(c Clock) {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	erh.clock = c
+}
+
+func (erh *EasyRingHandler) Write(data []byte) (int, error) {
+	return erh.WriteRecord(&Record{Level: LOG_LEVEL_INFO, Time: time.Now()}, data)
+}
+
+func (erh *EasyRingHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	stored := append([]byte(nil), data...)
+	if erh.MaxRecordSize > 0 && len(stored) > erh.MaxRecordSize {
+		// keep the line ending so dumped records stay one per line
+		body := bytes.TrimRight(stored, "\r\n")
+		eol := string(stored[len(body):])
+		if erh.MaxRecordSize > len(eol) {
+			stored = append(truncateRecord(body, erh.MaxRecordSize-len(eol)), eol...)
+		} else {
+			stored = truncateRecord(stored, erh.MaxRecordSize)
+		}
+	}
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	erh.records = append(erh.records, ringRecord{time: r.Time, added: erh.clock.Now(), level: r.Level, data: stored})
+	erh.size += len(stored)
+	for erh.size > erh.maxBytes && erh.head < len(erh.records) {
+		erh.evictOldest()
+	}
+	for erh.MaxRecords > 0 && len(erh.records)-erh.head > erh.MaxRecords {
+		erh.evictOldest()
+	}
+	erh.expire()
+	return len(data), nil
+}
+
+// expire evicts the records older than MaxAge and compacts the ring.
+func (erh *EasyRingHandler) expire() {
+	if erh.MaxAge > 0 {
+		cutoff := erh.clock.Now().Add(-erh.MaxAge)
+		for erh.head < len(erh.records) && erh.records[erh.head].added.Before(cutoff) {
+			erh.evictOldest()
+		}
+	}
+	erh.compact()
+}
+
+func (erh *EasyRingHandler) evictOldest() {
+	erh.size -= len(erh.records[erh.head].data)
+	erh.records[erh.head] = ringRecord{}
+	erh.head++
+}
+
+// compact drops evicted slots once they make up half of the slice.
+func (erh *EasyRingHandler) compact() {
+	if erh.head > 0 && erh.head*2 >= len(erh.records) {
+		n := copy(erh.records, erh.records[erh.head:])
+		for i := n; i < len(erh.records); i++ {
+			erh.records[i] = ringRecord{}
+		}
+		erh.records = erh.records[:n]
+		erh.head = 0
+	}
+}
+
+// Records returns copies of the buffered records, oldest first.
+func (erh *EasyRingHandler) Records() [][]byte {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	erh.expire()
+	records := make([][]byte, 0, len(erh.records)-erh.head)
+	for _, rr := range erh.records[erh.head:] {
+		records = append(records, append([]byte(nil), rr.data...))
+	}
+	return records
+}
+
+// WriteTo writes the buffered records to w, oldest first.
+func (erh *EasyRingHandler) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for _, data := range erh.Records() {
+		n, err := w.Write(data)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// Len returns the number of buffered records and their size in bytes.
+func (erh *EasyRingHandler) Len() (int, int) {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	erh.expire()
+	return len(erh.records) - erh.head, erh.size
+}
+
+func (erh *EasyRingHandler) Reset() {
+	erh.mutex.Lock()
+	defer erh.mutex.Unlock()
+	erh.records = nil
+	erh.head = 0
+	erh.size = 0
+}
+
+// ServeHTTP serves the buffered records as text, oldest first, e.g. as
+// /debug/logs; ?level=WARN leaves out the records below WARN.
+func (erh *EasyRingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	level := LOG_LEVEL_DEBUG
+	if name := req.URL.Query().Get("level"); name != "" {
+		level = getLogLevelInt(strings.ToUpper(name))
+	}
+	erh.mutex.Lock()
+	erh.expire()
+	var records [][]byte
+	for _, rr := range erh.records[erh.head:] {
+		if rr.level >= level {
+			records = append(records, append([]byte(nil), rr.data...))
+		}
+	}
+	erh.mutex.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, data := range records {
+		w.Write(data)
+	}
+}
+
+func (erh *EasyRingHandler) Describe() string {
+	desc := "memory ring " + strconv.Itoa(erh.maxBytes) + " bytes"
+	if erh.MaxRecords > 0 {
+		desc += ", " + strconv.Itoa(erh.MaxRecords) + " records"
+	}
+	if erh.MaxAge > 0 {
+		desc += ", " + erh.MaxAge.String()
+	}
+	return desc
+}
@@ -0,0 +1,238 @@
+package elog
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLColumns maps record parts to table columns, an empty name skips the part.
+type SQLColumns struct {
+	Time    string
+	Level   string
+	Logger  string
+	Caller  string
+	Message string
+	Fields  string // JSON object
+}
+
+var DefaultSQLColumns = SQLColumns{
+	Time:    "time",
+	Level:   "level",
+	Logger:  "logger",
+	Caller:  "caller",
+	Message: "msg",
+	Fields:  "fields",
+}
+
+// SQLConfig configures EasySQLHandler. DB is opened by the caller with the
+// driver of its choice (PostgreSQL, SQLite, MySQL...).
+type SQLConfig struct {
+	DB      *sql.DB
+	Table   string
+	Columns SQLColumns
+	// Placeholder is "?" (SQLite, MySQL, default) or "$" for PostgreSQL
+	// style $1, $2... parameters.
+	Placeholder string
+	// BatchSize records are inserted in one transaction with a prepared
+	// statement; Flush inserts a partial batch.
+	BatchSize int
+}
+
+// EasySQLHandler inserts records into a table, batched in transactions.
+type EasySQLHandler struct {
+	db      *sql.DB
+	table   string
+	query   string
+	columns SQLColumns
+	size    int
+	mutex   sync.Mutex
+	batch   [][]interface{}
+	written int64
+	failed  int64
+	lastErr error
+}
+
+func NewEasySQLHandler(config SQLConfig) (*EasySQLHandler, error) {
+	if config.DB == nil {
+		return nil, errors.New("elog: sql db required")
+	}
+	if !isSQLIdentifier(config.Table) {
+		return nil, errors.New("elog: bad sql table name " + strconv.Quote(config.Table))
+	}
+	if config.Columns == (SQLColumns{}) {
+		config.Columns = DefaultSQLColumns
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	c := config.Columns
+	var names []string
+	for _, name := range []string{c.Time, c.Level, c.Logger, c.Caller, c.Message, c.Fields} {
+		if name == "" {
+			continue
+		}
+		if !isSQLIdentifier(name) {
+			return nil, errors.New("elog: bad sql column name " + strconv.Quote(name))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("elog: no sql columns")
+	}
+	params := make([]string, len(names))
+	for i := range params {
+		if config.Placeholder == "$" {
+			params[i] = "$" + strconv.Itoa(i+1)
+		} else {
+			params[i] = "?"
+		}
+	}
+	return &EasySQLHandler{
+		db:      config.DB,
+		table:   config.Table,
+		query:   "INSERT INTO " + config.Table + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(params, ", ") + ")",
+		columns: c,
+		size:    config.BatchSize,
+	}, nil
+}
+
+func isSQLIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_' || c == '.' && i > 0:
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Write stores raw bytes as the message of an INFO row.
+func (esh *EasySQLHandler) Write(data []byte) (int, error) {
+	r := &Record{Level: LOG_LEVEL_INFO, Time: time.Now(), Message: string(trimNewline(data))}
+	return esh.WriteRecord(r, data)
+}
+
+func (esh *EasySQLHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	c := esh.columns
+	var row []interface{}
+	if c.Time != "" {
+		row = append(row, r.Time)
+	}
+	if c.Level != "" {
+		row = append(row, getLogLevelString(r.Level))
+	}
+	if c.Logger != "" {
+		row = append(row, r.Name)
+	}
+	if c.Caller != "" {
+		caller := ""
+		if r.File != "" {
+			caller = r.File + ":" + strconv.Itoa(r.Line)
+		}
+		row = append(row, caller)
+	}
+	if c.Message != "" {
+		row = append(row, r.Message)
+	}
+	if c.Fields != "" {
+		var buf bytes.Buffer
+		writeJSONObject(&buf, r.Fields)
+		row = append(row, buf.String())
+	}
+	esh.mutex.Lock()
+	defer esh.mutex.Unlock()
+	esh.batch = append(esh.batch, row)
+	if len(esh.batch) >= esh.size {
+		if err := esh.insert(); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// insert writes the pending batch in one transaction. A failed batch is
+// dropped and counted, it is not retried.
+func (esh *EasySQLHandler) insert() error {
+	if len(esh.batch) == 0 {
+		return nil
+	}
+	batch := esh.batch
+	esh.batch = nil
+	err := esh.insertBatch(batch)
+	if err != nil {
+		esh.failed += int64(len(batch))
+		esh.lastErr = err
+		return err
+	}
+	esh.written += int64(len(batch))
+	return nil
+}
+
+func (esh *EasySQLHandler) insertBatch(batch [][]interface{}) error {
+	tx, err := esh.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(esh.query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, row := range batch {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	stmt.Close()
+	return tx.Commit()
+}
+
+func (esh *EasySQLHandler) Flush() {
+	esh.FlushErr()
+}
+
+// FlushErr inserts the pending batch and returns the error of a failed
+// insert.
+func (esh *EasySQLHandler) FlushErr() error {
+	esh.mutex.Lock()
+	defer esh.mutex.Unlock()
+	return esh.insert()
+}
+
+// Close inserts the pending batch, the DB stays open and owned by the caller.
+func (esh *EasySQLHandler) Close() error {
+	esh.mutex.Lock()
+	defer esh.mutex.Unlock()
+	return esh.insert()
+}
+
+// SQLStats reports the insert counters of the handler.
+type SQLStats struct {
+	Written   int64
+	Failed    int64
+	Pending   int
+	LastError error
+}
+
+func (esh *EasySQLHandler) Stats() SQLStats {
+	esh.mutex.Lock()
+	defer esh.mutex.Unlock()
+	return SQLStats{Written: esh.written, Failed: esh.failed, Pending: len(esh.batch), LastError: esh.lastErr}
+}
+
+func (esh *EasySQLHandler) Describe() string {
+	return "sql table " + esh.table
+}
@@ -0,0 +1,432 @@
+package elog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TCPConfig configures EasyTCPHandler.
+type TCPConfig struct {
+	// Endpoints are host:port addresses in order of preference; records go
+	// to the first healthy one.
+	Endpoints    []string
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// ResolveInterval is how often the host of the current endpoint is
+	// resolved again; the connection is reopened when its address is gone
+	// from DNS. Preferred endpoints are retried at the same interval.
+	// 30s by default, negative disables both.
+	ResolveInterval time.Duration
+	// A failed endpoint is skipped for RetryBackoff, doubled on every
+	// further failure up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	Transport    TransportConfig
+}
+
+type tcpNote struct {
+	level   int
+	message string
+}
+
+type tcpEndpoint struct {
+	addr     string
+	failures int
+	retryAt  time.Time
+	lastErr  error
+}
+
+// EasyTCPHandler streams encoded records over TCP, optionally TLS, with
+// failover between endpoints. It reconnects on the next record after a
+// failure; the record that failed is retried once per endpoint. Writes
+// after Close fail.
+type EasyTCPHandler struct {
+	config    TCPConfig
+	mutex     sync.Mutex
+	endpoints []*tcpEndpoint
+	current   int
+	conn      net.Conn
+	raw       net.Conn // conn below TLS
+	writer    *bufio.Writer
+	buffered  int64
+	written   int64
+	failed    int64
+	failovers int64
+	lastErr   error
+	notes     []tcpNote
+	stop      chan struct{}
+	done      chan struct{}
+	closed    bool
+}
+
+func NewEasyTCPHandler(config TCPConfig) (*EasyTCPHandler, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("elog: tcp endpoints required")
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	if config.ResolveInterval == 0 {
+		config.ResolveInterval = 30 * time.Second
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+	if config.MaxBackoff < config.RetryBackoff {
+		config.MaxBackoff = time.Minute
+	}
+	eth := &EasyTCPHandler{config: config, current: -1}
+	for _, addr := range config.Endpoints {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, err
+		}
+		eth.endpoints = append(eth.endpoints, &tcpEndpoint{addr: addr})
+	}
+	eth.mutex.Lock()
+	err := eth.connect(time.Now())
+	eth.mutex.Unlock()
+	eth.report()
+	if err != nil {
+		return nil, err
+	}
+	if config.ResolveInterval > 0 {
+		eth.stop = make(chan struct{})
+		eth.done = make(chan struct{})
+		go eth.maintain()
+	}
+	return eth, nil
+}
+
+// connect dials the endpoints in order of preference, skipping those
+// backing off unless all are. It is called with mutex held.
+func (eth *EasyTCPHandler) connect(now time.Time) error {
+	order := make([]*tcpEndpoint, 0, len(eth.endpoints))
+	for _, ep := range eth.endpoints {
+		if !now.Before(ep.retryAt) {
+			order = append(order, ep)
+		}
+	}
+	if len(order) == 0 {
+		soonest := eth.endpoints[0]
+		for _, ep := range eth.endpoints {
+			if ep.retryAt.Before(soonest.retryAt) {
+				soonest = ep
+			}
+		}
+		order = append(order, soonest)
+	}
+	var err error
+	for _, ep := range order {
+		if err = eth.dial(ep); err == nil {
+			return nil
+		}
+		eth.markFailed(ep, err, now)
+	}
+	eth.lastErr = err
+	return err
+}
+
+func (eth *EasyTCPHandler) dial(ep *tcpEndpoint) error {
+	raw, err := eth.config.Transport.dial(ep.addr, eth.config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	conn, err := eth.config.Transport.dialTLS(raw, ep.addr, eth.config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	previous := eth.current
+	for i, e := range eth.endpoints {
+		if e == ep {
+			eth.current = i
+		}
+	}
+	if previous >= 0 && previous != eth.current {
+		eth.failovers++
+		eth.notes = append(eth.notes, tcpNote{LOG_LEVEL_INFO, "tcp: switched from " + eth.endpoints[previous].addr + " to " + ep.addr})
+	}
+	ep.failures = 0
+	ep.retryAt = time.Time{}
+	eth.conn = conn
+	eth.raw = raw
+	eth.writer = bufio.NewWriterSize(conn, 32*1024)
+	return nil
+}
+
+func (eth *EasyTCPHandler) markFailed(ep *tcpEndpoint, err error, now time.Time) {
+	backoff := eth.config.RetryBackoff
+	for i := 0; i < ep.failures && backoff < eth.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > eth.config.MaxBackoff {
+		backoff = eth.config.MaxBackoff
+	}
+	ep.failures++
+	ep.retryAt = now.Add(backoff)
+	ep.lastErr = err
+	eth.notes = append(eth.notes, tcpNote{LOG_LEVEL_WARN, "tcp: " + ep.addr + ": " + err.Error()})
+}
+
+// broken drops the connection after err, the records still buffered are
+// lost. It is called with mutex held.
+func (eth *EasyTCPHandler) broken(err error) {
+	eth.conn.Close()
+	eth.conn = nil
+	eth.raw = nil
+	eth.writer = nil
+	eth.failed += eth.buffered
+	eth.buffered = 0
+	eth.lastErr = err
+	eth.markFailed(eth.endpoints[eth.current], err, time.Now())
+}
+
+// report hands the events collected under mutex to the self log.
+func (eth *EasyTCPHandler) report() {
+	eth.mutex.Lock()
+	notes := eth.notes
+	eth.notes = nil
+	eth.mutex.Unlock()
+	for _, note := range notes {
+		selfLogf(note.level, "%s", note.message)
+	}
+}
+
+func (eth *EasyTCPHandler) Write(data []byte) (int, error) {
+	return eth.write(data, time.Time{})
+}
+
+// WriteRecordBefore gives up with ErrRecordDeadline when the record cannot
+// be buffered or sent before deadline; it does not dial for such records,
+// that is left to the records without a deadline.
+func (eth *EasyTCPHandler) WriteRecordBefore(r *Record, data []byte, deadline time.Time) (int, error) {
+	return eth.write(data, deadline)
+}
+
+func (eth *EasyTCPHandler) write(data []byte, deadline time.Time) (int, error) {
+	defer eth.report()
+	eth.mutex.Lock()
+	defer eth.mutex.Unlock()
+	if eth.closed {
+		eth.failed++
+		return 0, errors.New("elog: tcp handler closed")
+	}
+	bounded := !deadline.IsZero()
+	var err error
+	for attempt := 0; attempt < len(eth.endpoints); attempt++ {
+		now := time.Now()
+		if bounded && (eth.conn == nil || !now.Before(deadline)) {
+			eth.failed++
+			return 0, ErrRecordDeadline
+		}
+		if eth.conn == nil {
+			if err = eth.connect(now); err != nil {
+				break
+			}
+		}
+		writeDeadline := now.Add(eth.config.WriteTimeout)
+		if bounded && deadline.Before(writeDeadline) {
+			writeDeadline = deadline
+		}
+		eth.conn.SetWriteDeadline(writeDeadline)
+		// a record that does not fit is sent on its own, after those
+		// buffered, so each is counted written once on the socket
+		err = nil
+		if len(data) > eth.writer.Available() {
+			err = eth.send()
+		}
+		if err == nil {
+			if _, err = eth.writer.Write(data); err == nil {
+				if eth.writer.Buffered() == 0 {
+					eth.written++
+				} else {
+					eth.buffered++
+				}
+				return len(data), nil
+			}
+			eth.broken(err)
+		}
+		if bounded && !time.Now().Before(deadline) {
+			eth.failed++
+			return 0, ErrRecordDeadline
+		}
+	}
+	eth.failed++
+	return 0, err
+}
+
+func (eth *EasyTCPHandler) Flush() {
+	eth.FlushErr()
+}
+
+func (eth *EasyTCPHandler) FlushErr() error {
+	defer eth.report()
+	eth.mutex.Lock()
+	defer eth.mutex.Unlock()
+	return eth.flush()
+}
+
+func (eth *EasyTCPHandler) flush() error {
+	if eth.conn == nil {
+		return nil
+	}
+	eth.conn.SetWriteDeadline(time.Now().Add(eth.config.WriteTimeout))
+	return eth.send()
+}
+
+// send writes the buffered records to the connection unless the peer
+// closed it, which a write would not notice, and counts them written. It
+// is called with mutex held and a write deadline set.
+func (eth *EasyTCPHandler) send() error {
+	if err := peerClosed(eth.raw); err != nil {
+		eth.broken(err)
+		return err
+	}
+	if err := eth.writer.Flush(); err != nil {
+		eth.broken(err)
+		return err
+	}
+	eth.written += eth.buffered
+	eth.buffered = 0
+	return nil
+}
+
+// maintain re-resolves the current endpoint and moves back to preferred
+// endpoints once their backoff expired.
+func (eth *EasyTCPHandler) maintain() {
+	defer close(eth.done)
+	ticker := time.NewTicker(eth.config.ResolveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-eth.stop:
+			return
+		}
+		eth.mutex.Lock()
+		current, conn := eth.current, eth.conn
+		eth.mutex.Unlock()
+		if conn == nil {
+			continue
+		}
+		moved := eth.addressMoved(eth.endpoints[current].addr, conn.RemoteAddr())
+		eth.mutex.Lock()
+		if eth.conn == conn && (moved || eth.preferredReady(time.Now())) {
+			if moved {
+				eth.notes = append(eth.notes, tcpNote{LOG_LEVEL_INFO, "tcp: " + eth.endpoints[current].addr + " no longer resolves to " + conn.RemoteAddr().String()})
+			}
+			eth.flush()
+			if eth.conn != nil {
+				eth.conn.Close()
+				eth.conn = nil
+				eth.raw = nil
+				eth.writer = nil
+			}
+			eth.connect(time.Now())
+		}
+		eth.mutex.Unlock()
+		eth.report()
+	}
+}
+
+// addressMoved reports whether addr no longer resolves to remote.
+func (eth *EasyTCPHandler) addressMoved(addr string, remote net.Addr) bool {
+	tcpAddr, ok := remote.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eth.config.DialTimeout)
+	defer cancel()
+	ips, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip).Equal(tcpAddr.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// preferredReady reports whether an endpoint before the current one may
+// be tried again. It is called with mutex held.
+func (eth *EasyTCPHandler) preferredReady(now time.Time) bool {
+	for _, ep := range eth.endpoints[:eth.current] {
+		if !now.Before(ep.retryAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (eth *EasyTCPHandler) Close() error {
+	if eth.stop != nil {
+		close(eth.stop)
+		<-eth.done
+		eth.stop = nil
+	}
+	defer eth.report()
+	eth.mutex.Lock()
+	defer eth.mutex.Unlock()
+	eth.closed = true
+	err := eth.flush()
+	if eth.conn != nil {
+		if cerr := eth.conn.Close(); err == nil {
+			err = cerr
+		}
+		eth.conn = nil
+		eth.raw = nil
+		eth.writer = nil
+	}
+	return err
+}
+
+// TCPStats reports the counters of the handler. Written counts the writes
+// flushed to a connection, Failed those lost with one.
+type TCPStats struct {
+	Endpoint  string
+	Written   int64
+	Failed    int64
+	Buffered  int64
+	Failovers int64
+	LastError error
+	Endpoints []TCPEndpointStats
+}
+
+type TCPEndpointStats struct {
+	Addr      string
+	Healthy   bool
+	Failures  int
+	RetryAt   time.Time
+	LastError error
+}
+
+func (eth *EasyTCPHandler) Stats() TCPStats {
+	eth.mutex.Lock()
+	defer eth.mutex.Unlock()
+	stats := TCPStats{Written: eth.written, Failed: eth.failed, Buffered: eth.buffered, Failovers: eth.failovers, LastError: eth.lastErr}
+	if eth.conn != nil {
+		stats.Endpoint = eth.endpoints[eth.current].addr
+	}
+	now := time.Now()
+	for _, ep := range eth.endpoints {
+		stats.Endpoints = append(stats.Endpoints, TCPEndpointStats{Addr: ep.addr, Healthy: !now.Before(ep.retryAt), Failures: ep.failures, RetryAt: ep.retryAt, LastError: ep.lastErr})
+	}
+	return stats
+}
+
+func (eth *EasyTCPHandler) Describe() string {
+	return "tcp://" + strings.Join(eth.config.Endpoints, ",")
+}
@@ -0,0 +1,178 @@
+package elog
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// UnixConfig configures EasyUnixHandler.
+type UnixConfig struct {
+	Path         string
+	DialTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Fallback receives the records, as encoded by the logger, while the
+	// socket cannot be reached, e.g. os.Stderr; they are dropped when nil.
+	Fallback io.Writer
+}
+
+// EasyUnixHandler sends every record as one JSON line to a unix socket,
+// the client end of EasyRelay and ServeRecords. Records are encoded with
+// JSONEncoder whatever the encoder of the logger, so they arrive with their
+// level, caller and fields. The socket is dialed on the first record, which
+// suits short-lived commands: Close, or Shutdown of the logger, sends the
+// buffered records.
+type EasyUnixHandler struct {
+	config   UnixConfig
+	mutex    sync.Mutex
+	conn     net.Conn
+	writer   *bufio.Writer
+	encoder  JSONEncoder
+	written  int64
+	failed   int64
+	fellBack int64
+	lastErr  error
+	down     bool
+	notes    []tcpNote
+}
+
+func NewEasyUnixHandler(config UnixConfig) *EasyUnixHandler {
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	return &EasyUnixHandler{config: config}
+}
+
+func (euh *EasyUnixHandler) connect() error {
+	conn, err := net.DialTimeout("unix", euh.config.Path, euh.config.DialTimeout)
+	if err != nil {
+		return err
+	}
+	euh.conn = conn
+	euh.writer = bufio.NewWriterSize(conn, 32*1024)
+	return nil
+}
+
+func (euh *EasyUnixHandler) broken(err error) {
+	if euh.conn != nil {
+		euh.conn.Close()
+		euh.conn = nil
+	}
+	if !euh.down {
+		euh.down = true
+		euh.notes = append(euh.notes, tcpNote{LOG_LEVEL_WARN, euh.Describe() + ": " + err.Error()})
+	}
+	euh.lastErr = err
+}
+
+func (euh *EasyUnixHandler) Write(data []byte) (int, error) {
+	r := &Record{Level: LOG_LEVEL_INFO, Time: time.Now(), Message: string(trimNewline(data))}
+	return euh.WriteRecord(r, data)
+}
+
+func (euh *EasyUnixHandler) WriteRecord(r *Record, data []byte) (int, error) {
+	euh.mutex.Lock()
+	err := euh.send(r)
+	if err != nil {
+		euh.failed++
+		if euh.config.Fallback != nil {
+			euh.fellBack++
+			euh.config.Fallback.Write(data)
+		}
+	}
+	euh.mutex.Unlock()
+	euh.report()
+	if err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (euh *EasyUnixHandler) send(r *Record) error {
+	if euh.conn == nil {
+		if err := euh.connect(); err != nil {
+			euh.broken(err)
+			return err
+		}
+		if euh.down {
+			euh.down = false
+			euh.notes = append(euh.notes, tcpNote{LOG_LEVEL_INFO, euh.Describe() + ": reconnected"})
+		}
+	}
+	euh.conn.SetWriteDeadline(time.Now().Add(euh.config.WriteTimeout))
+	if err := euh.encoder.Encode(euh.writer, r); err != nil {
+		euh.broken(err)
+		return err
+	}
+	euh.written++
+	return nil
+}
+
+// report hands the connection changes collected under mutex to the self
+// log.
+func (euh *EasyUnixHandler) report() {
+	euh.mutex.Lock()
+	notes := euh.notes
+	euh.notes = nil
+	euh.mutex.Unlock()
+	for _, note := range notes {
+		selfLogf(note.level, "%s", note.message)
+	}
+}
+
+func (euh *EasyUnixHandler) Flush() {
+	euh.mutex.Lock()
+	euh.flush()
+	euh.mutex.Unlock()
+	euh.report()
+}
+
+func (euh *EasyUnixHandler) flush() error {
+	if euh.conn == nil {
+		return nil
+	}
+	euh.conn.SetWriteDeadline(time.Now().Add(euh.config.WriteTimeout))
+	if err := euh.writer.Flush(); err != nil {
+		euh.broken(err)
+		return err
+	}
+	return nil
+}
+
+func (euh *EasyUnixHandler) Close() error {
+	euh.mutex.Lock()
+	defer euh.mutex.Unlock()
+	err := euh.flush()
+	if euh.conn != nil {
+		if cerr := euh.conn.Close(); err == nil {
+			err = cerr
+		}
+		euh.conn = nil
+	}
+	return err
+}
+
+// UnixStats reports the records sent by the handler, those that could not
+// be sent and how many of those went to the fallback writer.
+type UnixStats struct {
+	Written   int64
+	Failed    int64
+	Fallback  int64
+	Connected bool
+	LastError error
+}
+
+func (euh *EasyUnixHandler) Stats() UnixStats {
+	euh.mutex.Lock()
+	defer euh.mutex.Unlock()
+	return UnixStats{Written: euh.written, Failed: euh.failed, Fallback: euh.fellBack, Connected: euh.conn != nil, LastError: euh.lastErr}
+}
+
+func (euh *EasyUnixHandler) Describe() string {
+	return "unix://" + euh.config.Path
+}
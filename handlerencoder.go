@@ -0,0 +1,90 @@
+package elog
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+)
+
+// HandlerEncoder is implemented by handlers that choose their own encoder
+// instead of the logger's.
+type HandlerEncoder interface {
+	Encoder() Encoder
+}
+
+type handlerEncoder struct {
+	handler io.Writer
+	encoder Encoder
+}
+
+// WithHandlerEncoder encodes the records of handler, the logger's handler,
+// a route handler or os.Stderr for logToStderr, with enc instead of the
+// logger's encoder, e.g. colored text on the console and JSON in the file.
+func WithHandlerEncoder(handler io.Writer, enc Encoder) Option {
+	return func(el *EasyLogger) {
+		for i, he := range el.handlerEncoders {
+			if he.handler == handler {
+				el.handlerEncoders[i].encoder = enc
+				return
+			}
+		}
+		el.handlerEncoders = append(el.handlerEncoders, handlerEncoder{handler: handler, encoder: enc})
+	}
+}
+
+// encoderFor returns the encoder of the records written to w.
+func (el *EasyLogger) encoderFor(w io.Writer, config *loggerConfig) Encoder {
+	if he, ok := w.(HandlerEncoder); ok {
+		if enc := he.Encoder(); enc != nil {
+			return enc
+		}
+	}
+	for _, he := range el.handlerEncoders {
+		if he.handler == w {
+			return he.encoder
+		}
+	}
+	return config.encoder
+}
+
+// recordEncodings holds the encodings of one record, so that it is encoded
+// once per encoder however many handlers use it.
+type recordEncodings struct {
+	r       *Record
+	entries []recordEncoding
+}
+
+type recordEncoding struct {
+	encoder Encoder
+	buf     *bytes.Buffer
+	err     error
+}
+
+func (re *recordEncodings) get(enc Encoder) (*bytes.Buffer, error) {
+	for _, e := range re.entries {
+		if sameEncoder(e.encoder, enc) {
+			return e.buf, e.err
+		}
+	}
+	buf := getJSONBuffer()
+	err := enc.Encode(buf, re.r)
+	re.entries = append(re.entries, recordEncoding{encoder: enc, buf: buf, err: err})
+	return buf, err
+}
+
+func (re *recordEncodings) release() {
+	for _, e := range re.entries {
+		putJSONBuffer(e.buf)
+	}
+	re.entries = nil
+}
+
+// sameEncoder compares encoders without panicking on those that are not
+// comparable, which are never the same.
+func sameEncoder(a, b Encoder) bool {
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) || !ta.Comparable() {
+		return false
+	}
+	return a == b
+}
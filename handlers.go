@@ -0,0 +1,147 @@
+package elog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Describer is implemented by handlers that can tell where their output
+// goes, e.g. a file path or a server address.
+type Describer interface {
+	Describe() string
+}
+
+// HandlerInfo describes a handler of a logger and the records the logger
+// wrote to it.
+type HandlerInfo struct {
+	Type          string
+	Destination   string
+	Tenant        string
+	Level         string
+	Records       int64
+	BytesWritten  int64
+	LastError     error
+	LastErrorTime time.Time
+	// Route is the tag of the route the handler serves, "*" for any.
+	Route string
+	// Distributions, nil unless the logger was created WithHistograms.
+	RecordSize    *Histogram
+	WriteLatency  *Histogram
+	FlushDuration *Histogram
+}
+
+type handlerStat struct {
+	records       int64
+	bytes         int64
+	lastErr       error
+	lastErrorTime time.Time
+	unflushedErr  error // first write error since the last flush
+	histograms    *handlerHistograms
+}
+
+func (hs *handlerStat) add(el *EasyLogger, n int64, err error, start time.Time) {
+	hs.records++
+	hs.bytes += n
+	if err != nil {
+		hs.lastErr = err
+		hs.lastErrorTime = el.clock.Now()
+		if hs.unflushedErr == nil {
+			hs.unflushedErr = err
+		}
+	}
+	hs.observeWrite(n, start)
+}
+
+// flush flushes w and returns its first error since the previous flush, of
+// a write or of the flush itself.
+func (hs *handlerStat) flush(el *EasyLogger, w io.Writer) error {
+	start := el.startTimer()
+	err := flushHandler(w)
+	hs.observeFlush(start)
+	if err != nil {
+		hs.lastErr = err
+		hs.lastErrorTime = el.clock.Now()
+	}
+	if hs.unflushedErr != nil {
+		err, hs.unflushedErr = hs.unflushedErr, nil
+	}
+	return err
+}
+
+func (hs *handlerStat) info(w io.Writer, level string) HandlerInfo {
+	info := HandlerInfo{
+		Type:          fmt.Sprintf("%T", w),
+		Destination:   describeHandler(w),
+		Level:         level,
+		Records:       hs.records,
+		BytesWritten:  hs.bytes,
+		LastError:     hs.lastErr,
+		LastErrorTime: hs.lastErrorTime,
+	}
+	if h := hs.histograms; h != nil {
+		size, write, flush := h.size, h.write, h.flush
+		info.RecordSize, info.WriteLatency, info.FlushDuration = &size, &write, &flush
+	}
+	return info
+}
+
+// Handlers lists the handlers the logger writes to: its handler, stderr
+// when logToStderr is set and the tenant handlers created so far.
+func (el *EasyLogger) Handlers() []HandlerInfo {
+	config := el.getConfig()
+	level := getLogLevelString(config.level)
+	el.lock()
+	defer el.unlock()
+	handlers := []HandlerInfo{el.stat.info(el.writer, level)}
+	if config.logToStderr || el.stderrStat.records > 0 {
+		handlers = append(handlers, el.stderrStat.info(os.Stderr, level))
+	}
+	if el.tenancy != nil {
+		tenants := make([]string, 0, len(el.tenancy.handlers))
+		for tenant := range el.tenancy.handlers {
+			tenants = append(tenants, tenant)
+		}
+		sort.Strings(tenants)
+		for _, tenant := range tenants {
+			th := el.tenancy.handlers[tenant]
+			info := th.stat.info(th.writer, level)
+			info.Tenant = tenant
+			handlers = append(handlers, info)
+		}
+	}
+	for _, rt := range el.routeHandlers() {
+		info := rt.stat.info(rt.Handler, level)
+		info.Route = rt.Tag
+		if rt.Level != "" {
+			info.Level = rt.Level
+		}
+		if info.Route == "" {
+			info.Route = "*"
+		}
+		handlers = append(handlers, info)
+	}
+	return handlers
+}
+
+func Handlers() []HandlerInfo {
+	return logger.Handlers()
+}
+
+func describeHandler(w io.Writer) string {
+	switch w {
+	case os.Stdout:
+		return "stdout"
+	case os.Stderr:
+		return "stderr"
+	}
+	switch h := w.(type) {
+	case Describer:
+		return h.Describe()
+	case *os.File:
+		return h.Name()
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package elog
+
+import (
+	"math/bits"
+	"time"
+)
+
+const (
+	LOG_HISTOGRAM_BUCKETS = 64
+)
+
+// Histogram is a distribution over exponential buckets: bucket 0 counts
+// values below 1, bucket i values in [2^(i-1), 2^i). Sizes are in bytes,
+// durations in nanoseconds.
+type Histogram struct {
+	Count   int64
+	Sum     int64
+	Min     int64
+	Max     int64
+	Buckets [LOG_HISTOGRAM_BUCKETS]int64
+}
+
+func (h *Histogram) observe(v int64) {
+	if h.Count == 0 || v < h.Min {
+		h.Min = v
+	}
+	if v > h.Max {
+		h.Max = v
+	}
+	h.Count++
+	h.Sum += v
+	i := 0
+	if v > 0 {
+		i = bits.Len64(uint64(v))
+	}
+	if i >= LOG_HISTOGRAM_BUCKETS {
+		i = LOG_HISTOGRAM_BUCKETS - 1
+	}
+	h.Buckets[i]++
+}
+
+func (h Histogram) Mean() float64 {
+	if h.Count == 0 {
+		return 0
+	}
+	return float64(h.Sum) / float64(h.Count)
+}
+
+// Quantile estimates the q-quantile, 0 <= q <= 1, as the upper bound of the
+// bucket it falls in, never beyond Max.
+func (h Histogram) Quantile(q float64) int64 {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(h.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range h.Buckets {
+		seen += n
+		if seen < rank {
+			continue
+		}
+		if i == 0 {
+			return h.Min
+		}
+		upper := int64(1)<<uint(i) - 1
+		if i == LOG_HISTOGRAM_BUCKETS-1 || upper > h.Max {
+			return h.Max
+		}
+		return upper
+	}
+	return h.Max
+}
+
+type handlerHistograms struct {
+	size  Histogram
+	write Histogram
+	flush Histogram
+}
+
+// WithHistograms records the distributions of record sizes, handler write
+// latencies and flush durations per handler, reported by Handlers.
+func WithHistograms() Option {
+	return func(el *EasyLogger) {
+		el.histograms = true
+	}
+}
+
+// startTimer returns the start of a timed handler call, zero when the
+// logger keeps no histograms.
+func (el *EasyLogger) startTimer() time.Time {
+	if !el.histograms {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (hs *handlerStat) observeWrite(n int64, start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	if hs.histograms == nil {
+		hs.histograms = &handlerHistograms{}
+	}
+	hs.histograms.write.observe(int64(time.Since(start)))
+	hs.histograms.size.observe(n)
+}
+
+func (hs *handlerStat) observeFlush(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	if hs.histograms == nil {
+		hs.histograms = &handlerHistograms{}
+	}
+	hs.histograms.flush.observe(int64(time.Since(start)))
+}
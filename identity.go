@@ -0,0 +1,108 @@
+package elog
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	LOG_FIELD_IDENTITY = "identity"
+)
+
+// IdentitySource returns the workload identity of the process, e.g. its
+// SPIFFE ID.
+type IdentitySource func() (string, error)
+
+// SPIFFEFromCertFile reads the SPIFFE ID from the URI SAN of the first
+// certificate in a PEM file, such as the X.509 SVID written by a SPIFFE
+// helper.
+func SPIFFEFromCertFile(path string) IdentitySource {
+	return func() (string, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		for {
+			var block *pem.Block
+			if block, data = pem.Decode(data); block == nil {
+				return "", errors.New("elog: no certificate in " + path)
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return "", err
+			}
+			for _, uri := range cert.URIs {
+				if uri.Scheme == "spiffe" {
+					return uri.String(), nil
+				}
+			}
+			return "", errors.New("elog: no SPIFFE ID in " + path)
+		}
+	}
+}
+
+// IdentityFromFile reads the identity from a file, e.g. a mounted service
+// account name.
+func IdentityFromFile(path string) IdentitySource {
+	return func() (string, error) {
+		data, err := ioutil.ReadFile(path)
+		return strings.TrimSpace(string(data)), err
+	}
+}
+
+// IdentityFromEnv reads the identity from an environment variable.
+func IdentityFromEnv(name string) IdentitySource {
+	return func() (string, error) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+		return "", errors.New("elog: " + name + " not set")
+	}
+}
+
+type identityCache struct {
+	source  IdentitySource
+	refresh time.Duration
+	mutex   sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// WithIdentity adds the identity read from source to every record under
+// the "identity" field. The identity is read again after refresh, since
+// certificates rotate; on failure the last identity is kept.
+func WithIdentity(source IdentitySource, refresh time.Duration) Option {
+	return func(el *EasyLogger) {
+		ic := &identityCache{source: source, refresh: refresh}
+		el.addFieldResolver(fieldResolver{key: LOG_FIELD_IDENTITY, level: LOG_LEVEL_DEBUG, fn: ic.get})
+	}
+}
+
+func (ic *identityCache) get() interface{} {
+	ic.mutex.Lock()
+	now := time.Now()
+	if now.Before(ic.expires) {
+		defer ic.mutex.Unlock()
+		return ic.value
+	}
+	ic.expires = now.Add(ic.refresh)
+	value, err := ic.source()
+	if err == nil {
+		ic.value = value
+	}
+	value = ic.value
+	ic.mutex.Unlock()
+	if err != nil {
+		selfLogf(LOG_LEVEL_WARN, "identity: %v", err)
+	}
+	return value
+}
@@ -0,0 +1,64 @@
+package elog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+type idleFlush struct {
+	last    int64 // nanoseconds since start of the last record
+	pending int32
+	idle    time.Duration
+	start   time.Time
+	wake    chan struct{}
+}
+
+// WithIdleFlush flushes the handler once no record arrived for idle, so
+// low-volume services get their records out without waiting for the next
+// flush tick while bursts are still written in batches.
+func WithIdleFlush(idle time.Duration) Option {
+	return func(el *EasyLogger) {
+		el.idle = &idleFlush{idle: idle, start: time.Now(), wake: make(chan struct{}, 1)}
+		go el.idle.run(el)
+	}
+}
+
+// touch notes a record, waking the flusher if it sleeps.
+func (ifl *idleFlush) touch() {
+	atomic.StoreInt64(&ifl.last, int64(time.Since(ifl.start)))
+	if atomic.LoadInt32(&ifl.pending) == 0 && atomic.CompareAndSwapInt32(&ifl.pending, 0, 1) {
+		select {
+		case ifl.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (ifl *idleFlush) run(el *EasyLogger) {
+	for {
+		select {
+		case <-ifl.wake:
+		case <-el.done:
+			return
+		}
+		for {
+			wait := ifl.idle - (time.Since(ifl.start) - time.Duration(atomic.LoadInt64(&ifl.last)))
+			if wait <= 0 {
+				break
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-el.done:
+				timer.Stop()
+				return
+			}
+		}
+		atomic.StoreInt32(&ifl.pending, 0)
+		el.lock()
+		if atomic.LoadInt32(&el.closed) == 0 {
+			el.flushWriters()
+		}
+		el.unlock()
+	}
+}
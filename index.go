@@ -0,0 +1,131 @@
+package elog
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LOG_INDEX_SUFFIX   = ".idx"
+	LOG_INDEX_INTERVAL = 4 * 1024 * 1024
+)
+
+// EnableIndex maintains a sidecar index next to every log file, "app-date.log.idx",
+// with one "<unixnano> <offset>" line every interval bytes (LOG_INDEX_INTERVAL
+// when <= 0). SeekTime and OpenLogAt use it to jump into large files.
+func (efh *EasyFileHandler) EnableIndex(interval int64) {
+	if interval <= 0 {
+		interval = LOG_INDEX_INTERVAL
+	}
+	efh.index = &fileIndex{interval: interval}
+	if efh.file != nil {
+		efh.index.open(efh.file.Name()+LOG_INDEX_SUFFIX, efh.offset)
+	}
+}
+
+type fileIndex struct {
+	interval int64
+	next     int64
+	file     *os.File
+	midLine  bool
+}
+
+// open opens the index of a log file whose end is at offset, emptying the
+// index of a new log file so that no entry points into a former one.
+func (fi *fileIndex) open(path string, offset int64) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		selfLogf(LOG_LEVEL_ERROR, "index: %v", err)
+		return
+	}
+	fi.file = file
+	fi.next = offset
+	fi.midLine = false
+}
+
+// mark records the start of the record written at offset when the last
+// entry is at least interval bytes behind. Writes continuing a line (a
+// record body after its header) are never indexed.
+func (fi *fileIndex) mark(t time.Time, offset int64, data []byte) {
+	midLine := fi.midLine
+	if len(data) > 0 {
+		fi.midLine = data[len(data)-1] != '\n'
+	}
+	if fi.file == nil || midLine || offset < fi.next {
+		return
+	}
+	fi.file.WriteString(strconv.FormatInt(t.UnixNano(), 10) + " " + strconv.FormatInt(offset, 10) + "\n")
+	fi.next = offset + fi.interval
+}
+
+func (fi *fileIndex) close() {
+	if fi.file != nil {
+		fi.file.Close()
+		fi.file = nil
+	}
+}
+
+// SeekTime returns the offset in the log file at path from which reading
+// finds every record written at or after t, according to its sidecar index.
+// Without index it returns 0.
+func SeekTime(path string, t time.Time) (int64, error) {
+	file, err := os.Open(path + LOG_INDEX_SUFFIX)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	target := t.UnixNano()
+	var offset int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		ts, err1 := strconv.ParseInt(parts[0], 10, 64)
+		off, err2 := strconv.ParseInt(parts[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if ts >= target {
+			break
+		}
+		offset = off
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if info, err := os.Stat(path); err == nil && offset > info.Size() {
+		// the index can run ahead of data lost in a crash
+		offset = 0
+	}
+	return offset, nil
+}
+
+// OpenLogAt opens the log file at path positioned by SeekTime.
+func OpenLogAt(path string, t time.Time) (*os.File, error) {
+	offset, err := SeekTime(path, t)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,195 @@
+package elog
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	LOG_SYSLOG_EMERG   = 0
+	LOG_SYSLOG_ALERT   = 1
+	LOG_SYSLOG_CRIT    = 2
+	LOG_SYSLOG_ERR     = 3
+	LOG_SYSLOG_WARNING = 4
+	LOG_SYSLOG_NOTICE  = 5
+	LOG_SYSLOG_INFO    = 6
+	LOG_SYSLOG_DEBUG   = 7
+)
+
+var builtinLevels = []struct {
+	value    int
+	name     string
+	severity int
+}{
+	{LOG_LEVEL_DEBUG, "DEBUG", LOG_SYSLOG_DEBUG},
+	{LOG_LEVEL_INFO, "INFO", LOG_SYSLOG_INFO},
+	{LOG_LEVEL_WARN, "WARN", LOG_SYSLOG_WARNING},
+	{LOG_LEVEL_ERROR, "ERROR", LOG_SYSLOG_ERR},
+	{LOG_LEVEL_FATAL, "FATAL", LOG_SYSLOG_CRIT},
+	{LOG_LEVEL_NONE, "NONE", LOG_SYSLOG_EMERG},
+}
+
+var syslogSeverityNames = map[string]int{
+	"EMERG":     LOG_SYSLOG_EMERG,
+	"EMERGENCY": LOG_SYSLOG_EMERG,
+	"ALERT":     LOG_SYSLOG_ALERT,
+	"CRIT":      LOG_SYSLOG_CRIT,
+	"CRITICAL":  LOG_SYSLOG_CRIT,
+	"ERR":       LOG_SYSLOG_ERR,
+	"WARNING":   LOG_SYSLOG_WARNING,
+	"NOTICE":    LOG_SYSLOG_NOTICE,
+}
+
+// levelTable maps level values to their labels and back. Like
+// loggerConfig it is replaced, never modified, once published.
+type levelTable struct {
+	names      map[int]string
+	values     map[string]int
+	tokens     map[int]string
+	severities map[int]int
+}
+
+var levelMutex sync.Mutex
+var levelTableValue = newLevelTableValue()
+
+func newLevelTableValue() *atomic.Value {
+	lt := &levelTable{names: map[int]string{}, values: map[string]int{}, tokens: map[int]string{}, severities: map[int]int{}}
+	for _, level := range builtinLevels {
+		lt.names[level.value] = level.name
+		lt.values[level.name] = level.value
+		lt.tokens[level.value] = "[" + level.name + "]"
+		lt.severities[level.value] = level.severity
+	}
+	v := &atomic.Value{}
+	v.Store(lt)
+	return v
+}
+
+func currentLevels() *levelTable {
+	return levelTableValue.Load().(*levelTable)
+}
+
+func (lt *levelTable) clone() *levelTable {
+	nlt := &levelTable{names: map[int]string{}, values: map[string]int{}, tokens: map[int]string{}, severities: map[int]int{}}
+	for k, v := range lt.names {
+		nlt.names[k] = v
+	}
+	for k, v := range lt.values {
+		nlt.values[k] = v
+	}
+	for k, v := range lt.tokens {
+		nlt.tokens[k] = v
+	}
+	for k, v := range lt.severities {
+		nlt.severities[k] = v
+	}
+	return nlt
+}
+
+// SetLevelName changes the label written for level, e.g.
+// SetLevelName(LOG_LEVEL_WARN, "WARNING") or a localized name. The new label
+// is also accepted when parsing levels, next to the built-in one.
+func SetLevelName(level int, name string) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+	lt := currentLevels().clone()
+	if old, ok := lt.names[level]; ok && !isBuiltinLevelName(old) {
+		delete(lt.values, old)
+	}
+	lt.names[level] = name
+	lt.values[name] = level
+	lt.tokens[level] = "[" + name + "]"
+	levelTableValue.Store(lt)
+}
+
+func isBuiltinLevelName(name string) bool {
+	for _, level := range builtinLevels {
+		if level.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterLevel adds a custom level, e.g. RegisterLevel("NOTICE", 25) sorts
+// between INFO (20) and WARN (30). It filters like the built-in levels, is
+// accepted by -logLevel and SetLevel, and is logged with Log/Logf. Its
+// syslog severity follows the level name when it is a syslog severity name
+// (NOTICE, CRITICAL, ...), otherwise that of the closest built-in level
+// below; SetLevelSeverity overrides it.
+func RegisterLevel(name string, value int) error {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+	lt := currentLevels()
+	if name == "" || strings.ContainsAny(name, "[] \t\r\n") {
+		return errors.New("elog: invalid level name " + strconv.Quote(name))
+	}
+	if _, ok := lt.values[name]; ok {
+		return errors.New("elog: level " + name + " already registered")
+	}
+	if old, ok := lt.names[value]; ok {
+		return errors.New("elog: level value " + strconv.Itoa(value) + " already used by " + old)
+	}
+	if value <= 0 || value >= LOG_LEVEL_NONE {
+		return errors.New("elog: level value " + strconv.Itoa(value) + " out of range")
+	}
+	lt = lt.clone()
+	lt.names[value] = name
+	lt.values[name] = value
+	lt.tokens[value] = "[" + name + "]"
+	if severity, ok := syslogSeverityNames[strings.ToUpper(name)]; ok {
+		lt.severities[value] = severity
+	} else {
+		lt.severities[value] = lt.severityBelow(value)
+	}
+	levelTableValue.Store(lt)
+	return nil
+}
+
+func (lt *levelTable) severityBelow(value int) int {
+	best := LOG_LEVEL_DEBUG
+	for _, level := range builtinLevels {
+		if level.value <= value && level.value > best {
+			best = level.value
+		}
+	}
+	return lt.severities[best]
+}
+
+// SetLevelSeverity sets the syslog severity (LOG_SYSLOG_*) handlers use
+// for level.
+func SetLevelSeverity(level int, severity int) {
+	levelMutex.Lock()
+	defer levelMutex.Unlock()
+	lt := currentLevels().clone()
+	lt.severities[level] = severity
+	levelTableValue.Store(lt)
+}
+
+// SyslogSeverity returns the syslog severity (LOG_SYSLOG_*) of level.
+func SyslogSeverity(level int) int {
+	lt := currentLevels()
+	if severity, ok := lt.severities[level]; ok {
+		return severity
+	}
+	return lt.severityBelow(level)
+}
+
+// Levels returns the names of all known levels ordered by value.
+func Levels() []string {
+	lt := currentLevels()
+	values := make([]int, 0, len(lt.names))
+	for value := range lt.names {
+		values = append(values, value)
+	}
+	sort.Ints(values)
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = lt.names[value]
+	}
+	return names
+}
@@ -0,0 +1,10 @@
+module github.com/starjiang/elog/logrushook
+
+go 1.13
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	github.com/starjiang/elog v0.0.0
+)
+
+replace github.com/starjiang/elog => ../
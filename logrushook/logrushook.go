@@ -0,0 +1,80 @@
+// Package logrushook sends logrus entries to an elog logger as records,
+// with their level, time, caller and fields, so both libraries share the
+// elog handlers while a project migrates:
+//
+//	logrus.AddHook(logrushook.New(log))
+//	logrus.SetOutput(ioutil.Discard)
+//
+// It is a module of its own so that elog does not depend on logrus.
+package logrushook
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/starjiang/elog"
+)
+
+// Hook is a logrus.Hook writing every entry to an elog logger.
+type Hook struct {
+	el     *elog.EasyLogger
+	levels []logrus.Level
+}
+
+// New returns a hook for all the logrus levels.
+func New(el *elog.EasyLogger) *Hook {
+	return &Hook{el: el, levels: logrus.AllLevels}
+}
+
+// NewLevels returns a hook for the given logrus levels only.
+func NewLevels(el *elog.EasyLogger, levels ...logrus.Level) *Hook {
+	return &Hook{el: el, levels: levels}
+}
+
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire emits the entry. The logger is flushed for fatal and panic entries,
+// as logrus exits or panics right after the hooks.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	level := Level(entry.Level)
+	r := &elog.Record{Level: level, Time: entry.Time, File: "???", Line: 1, Message: entry.Message, Context: entry.Context}
+	if entry.Caller != nil {
+		r.File, r.Line, r.PC = shortFileName(entry.Caller.File), entry.Caller.Line, entry.Caller.PC
+	}
+	if len(entry.Data) > 0 {
+		r.Fields = make(elog.Fields, len(entry.Data))
+		for k, v := range entry.Data {
+			r.Fields[k] = v
+		}
+	}
+	h.el.Emit(r)
+	if level >= elog.LOG_LEVEL_FATAL {
+		return h.el.Flush()
+	}
+	return nil
+}
+
+// Level maps a logrus level to the elog one: trace to DEBUG, panic to
+// FATAL.
+func Level(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return elog.LOG_LEVEL_FATAL
+	case logrus.ErrorLevel:
+		return elog.LOG_LEVEL_ERROR
+	case logrus.WarnLevel:
+		return elog.LOG_LEVEL_WARN
+	case logrus.InfoLevel:
+		return elog.LOG_LEVEL_INFO
+	}
+	return elog.LOG_LEVEL_DEBUG
+}
+
+func shortFileName(file string) string {
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		return file[slash+1:]
+	}
+	return file
+}
@@ -0,0 +1,29 @@
+//go:build go1.21
+// +build go1.21
+
+package elog
+
+import "log/slog"
+
+// resolveLogValuer resolves slog.LogValuer and slog.Value field values,
+// groups become maps.
+func resolveLogValuer(v interface{}) (interface{}, bool) {
+	switch t := v.(type) {
+	case slog.LogValuer:
+		return slogValue(slog.AnyValue(t).Resolve()), true
+	case slog.Value:
+		return slogValue(t.Resolve()), true
+	}
+	return nil, false
+}
+
+func slogValue(v slog.Value) interface{} {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	group := make(map[string]interface{}, len(v.Group()))
+	for _, attr := range v.Group() {
+		group[attr.Key] = slogValue(attr.Value.Resolve())
+	}
+	return group
+}
@@ -0,0 +1,8 @@
+//go:build !go1.21
+// +build !go1.21
+
+package elog
+
+func resolveLogValuer(v interface{}) (interface{}, bool) {
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package elog
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	LOG_MAX_RESOLVE_DEPTH = 8
+)
+
+// FieldMarshaler is implemented by types that control their own
+// representation as field values, e.g. to redact themselves. The value
+// returned is logged in their place.
+type FieldMarshaler interface {
+	MarshalLogField() interface{}
+}
+
+// resolveValue replaces a FieldMarshaler or slog.LogValuer field value
+// with the value it stands for, following chains of them. It reports
+// whether v was replaced.
+func resolveValue(v interface{}) (interface{}, bool) {
+	resolved := false
+	for i := 0; i < LOG_MAX_RESOLVE_DEPTH; i++ {
+		if fm, ok := v.(FieldMarshaler); ok {
+			v, resolved = fm.MarshalLogField(), true
+			continue
+		}
+		if lv, ok := resolveLogValuer(v); ok {
+			v, resolved = lv, true
+			continue
+		}
+		return v, resolved
+	}
+	return fmt.Sprintf("!elog(resolve loop %T)", v), true
+}
+
+// marshalText gives the JSON of a json.Marshaler for the text encoder,
+// unless the value has a String or Error method of its own.
+func marshalText(v interface{}) (string, bool) {
+	m, ok := v.(json.Marshaler)
+	if !ok {
+		return "", false
+	}
+	switch v.(type) {
+	case fmt.Stringer, error:
+		return "", false
+	}
+	b, err := m.MarshalJSON()
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
@@ -0,0 +1,55 @@
+package elog
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// ReportMemStats logs a summary of runtime.MemStats at level every interval
+// until the returned stop function is called or the logger is shut down.
+func (el *EasyLogger) ReportMemStats(interval time.Duration, level int) (stop func()) {
+	file, line, pc := getCaller(el.depth - 1)
+	ticker := el.clock.NewTicker(interval)
+	quit := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer ticker.Stop()
+		var lastGC uint32
+		for {
+			select {
+			case <-ticker.C():
+			case <-quit:
+				return
+			case <-el.done:
+				return
+			}
+			if !el.enabled(level) {
+				continue
+			}
+			var ms runtime.MemStats
+			runtime.ReadMemStats(&ms)
+			fields := Fields{
+				"heap_alloc":      ms.HeapAlloc,
+				"heap_inuse":      ms.HeapInuse,
+				"heap_objects":    ms.HeapObjects,
+				"sys":             ms.Sys,
+				"num_gc":          ms.NumGC,
+				"gc_since_last":   ms.NumGC - lastGC,
+				"pause_total":     time.Duration(ms.PauseTotalNs).String(),
+				"last_pause":      time.Duration(ms.PauseNs[(ms.NumGC+255)%256]).String(),
+				"gc_cpu_fraction": ms.GCCPUFraction,
+				"goroutines":      runtime.NumGoroutine(),
+			}
+			lastGC = ms.NumGC
+			el.record(&Record{Level: level, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: "memstats", Fields: fields})
+		}
+	}()
+	return func() {
+		once.Do(func() { close(quit) })
+	}
+}
+
+func ReportMemStats(interval time.Duration, level int) (stop func()) {
+	return logger.ReportMemStats(interval, level)
+}
@@ -0,0 +1,138 @@
+package elog
+
+import (
+	"container/heap"
+	"io"
+	"os"
+	"strconv"
+)
+
+// RotatedFiles returns the files of the rotation series of the log file at
+// path that exist, oldest first: path.N ... path.1, path.
+func RotatedFiles(path string) []string {
+	var files []string
+	for i := LOG_MAX_ROTATE_FILE_NUM - 1; i > 0; i-- {
+		if name := path + "." + strconv.Itoa(i); fileIsExist(name) {
+			files = append(files, name)
+		}
+	}
+	if fileIsExist(path) {
+		files = append(files, path)
+	}
+	return files
+}
+
+// mergeSource reads the records of a rotation series, file after file.
+type mergeSource struct {
+	files  []string
+	file   *os.File
+	reader *RecordReader
+	next   *Record
+	order  int
+}
+
+func (ms *mergeSource) advance(q *Query) error {
+	for {
+		if ms.reader == nil {
+			if len(ms.files) == 0 {
+				ms.next = nil
+				return nil
+			}
+			file, err := OpenLogAt(ms.files[0], q.Since())
+			if err != nil {
+				return err
+			}
+			ms.files = ms.files[1:]
+			ms.file = file
+			ms.reader = NewRecordReader(file)
+		}
+		r, err := ms.reader.Next()
+		if err == io.EOF {
+			ms.close()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if q.Match(r) {
+			ms.next = r
+			return nil
+		}
+	}
+}
+
+func (ms *mergeSource) close() {
+	if ms.file != nil {
+		ms.file.Close()
+		ms.file = nil
+	}
+	ms.reader = nil
+}
+
+type mergeHeap []*mergeSource
+
+func (mh mergeHeap) Len() int { return len(mh) }
+func (mh mergeHeap) Less(i, j int) bool {
+	if ti, tj := mh[i].next.Time, mh[j].next.Time; !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return mh[i].order < mh[j].order
+}
+func (mh mergeHeap) Swap(i, j int)       { mh[i], mh[j] = mh[j], mh[i] }
+func (mh *mergeHeap) Push(x interface{}) { *mh = append(*mh, x.(*mergeSource)) }
+func (mh *mergeHeap) Pop() interface{} {
+	old := *mh
+	ms := old[len(old)-1]
+	*mh = old[:len(old)-1]
+	return ms
+}
+
+// MergeFiles streams the records matching q, nil for all, of several log
+// files to fn in time order, e.g. the files of several days or of several
+// replicas for an incident timeline. Each path is read together with its
+// rotated backups (RotatedFiles). Records of equal time keep the order of
+// paths; each file is expected to be in time order itself.
+func MergeFiles(paths []string, q *Query, fn func(r *Record) error) error {
+	if q == nil {
+		q = &Query{}
+	}
+	var sources mergeHeap
+	defer func() {
+		for _, ms := range sources {
+			ms.close()
+		}
+	}()
+	for i, path := range paths {
+		files := RotatedFiles(path)
+		if len(files) == 0 {
+			if _, err := os.Stat(path); err != nil {
+				return err
+			}
+		}
+		ms := &mergeSource{files: files, order: i}
+		if err := ms.advance(q); err != nil {
+			ms.close()
+			return err
+		}
+		if ms.next != nil {
+			sources = append(sources, ms)
+		}
+	}
+	heap.Init(&sources)
+	for len(sources) > 0 {
+		ms := sources[0]
+		if err := fn(ms.next); err != nil {
+			return err
+		}
+		if err := ms.advance(q); err != nil {
+			return err
+		}
+		if ms.next == nil {
+			heap.Pop(&sources)
+			ms.close()
+		} else {
+			heap.Fix(&sources, 0)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package elog
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Limits of a map, slice or struct field value: deeper values become
+	// "...", further elements are summed up as "...(N more)" and a value
+	// encoding to more bytes is logged as its truncated JSON text.
+	LOG_FIELD_MAX_DEPTH = 8
+	LOG_FIELD_MAX_ITEMS = 100
+	LOG_FIELD_MAX_BYTES = 16 * 1024
+
+	// LOG_MASK replaces the value of struct fields tagged elog:"mask".
+	LOG_MASK = "***"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isNested reports whether v is a map, slice, array or struct, or a
+// pointer to one, that encodes itself field by field: not an error or a
+// JSON or text marshaler.
+func isNested(v interface{}) bool {
+	if _, ok := v.(error); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Array:
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+	default:
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(jsonMarshalerType) && !reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// appendNestedJSON appends v as nested JSON within the LOG_FIELD_MAX_*
+// limits.
+func appendNestedJSON(dst []byte, v interface{}) []byte {
+	start := len(dst)
+	dst = appendNestedValue(dst, reflect.ValueOf(v), 0, start)
+	if over(dst, start) {
+		dst = appendJSONString(dst[:start], cutNested(dst[start:]))
+	}
+	return dst
+}
+
+// nestedText is the JSON of v for the text encoder, cut like
+// appendNestedJSON does.
+func nestedText(v interface{}) string {
+	data := appendNestedValue(nil, reflect.ValueOf(v), 0, 0)
+	if over(data, 0) {
+		return cutNested(data)
+	}
+	return string(data)
+}
+
+func cutNested(data []byte) string {
+	text := string(data[:LOG_FIELD_MAX_BYTES])
+	for !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text + truncatedMark
+}
+
+// over reports whether the value begun at start outgrew its byte limit;
+// the encoding stops there, appendNestedJSON truncating it anyway.
+func over(dst []byte, start int) bool {
+	return len(dst)-start > LOG_FIELD_MAX_BYTES
+}
+
+// appendNestedValue appends v, the value itself at depth 0 and one of its
+// elements below, which are encoded like field values unless nested.
+func appendNestedValue(dst []byte, v reflect.Value, depth int, start int) []byte {
+	if depth > 0 && v.IsValid() && v.CanInterface() {
+		if iv := v.Interface(); !isNested(iv) {
+			return appendJSONValue(dst, iv)
+		}
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return append(dst, "null"...)
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return append(dst, "null"...)
+	}
+	if depth >= LOG_FIELD_MAX_DEPTH {
+		return appendJSONString(dst, "...")
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		return appendNestedMap(dst, v, depth, start)
+	case reflect.Struct:
+		dst = append(dst, '{')
+		dst, _ = appendNestedStruct(dst, v, depth, start, true)
+		return append(dst, '}')
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(dst, "null"...)
+		}
+	case reflect.Array:
+	default:
+		// fields promoted from unexported embedded structs
+		return appendJSONString(dst, fmt.Sprint(v))
+	}
+	dst = append(dst, '[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		if i == LOG_FIELD_MAX_ITEMS || over(dst, start) {
+			return append(appendJSONString(dst, "...("+strconv.Itoa(v.Len()-i)+" more)"), ']')
+		}
+		dst = appendNestedValue(dst, v.Index(i), depth+1, start)
+	}
+	return append(dst, ']')
+}
+
+func appendNestedMap(dst []byte, v reflect.Value, depth int, start int) []byte {
+	if v.IsNil() {
+		return append(dst, "null"...)
+	}
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		entries = append(entries, entry{mapKey(iter.Key()), iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+	dst = append(dst, '{')
+	for i, e := range entries {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		if i == LOG_FIELD_MAX_ITEMS || over(dst, start) {
+			dst = appendJSONString(dst, "...")
+			dst = append(dst, ':')
+			return append(appendJSONString(dst, strconv.Itoa(len(entries)-i)+" more"), '}')
+		}
+		dst = appendJSONString(dst, e.key)
+		dst = append(dst, ':')
+		dst = appendNestedValue(dst, e.value, depth+1, start)
+	}
+	return append(dst, '}')
+}
+
+// mapKey gives the JSON object key of a map key the way encoding/json
+// does for strings, integers and text marshalers.
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if b, err := tm.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(k.Interface())
+}
+
+// appendNestedStruct appends the exported fields of struct v, with the
+// names and omitempty of their json tags; embedded structs without a name
+// are inlined. Fields tagged elog:"omit" are left out and those tagged
+// elog:"mask", e.g. passwords and tokens, logged as LOG_MASK whatever their
+// value. It reports whether it wrote a field.
+func appendNestedStruct(dst []byte, v reflect.Value, depth int, start int, first bool) ([]byte, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		secret := f.Tag.Get("elog")
+		if secret == "omit" {
+			continue
+		}
+		name, opts := f.Name, ""
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			if tag == "-" {
+				continue
+			}
+			if comma := strings.IndexByte(tag, ','); comma >= 0 {
+				tag, opts = tag[:comma], tag[comma:]
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fv := v.Field(i)
+		if f.Anonymous && name == f.Name {
+			inner := fv
+			if inner.Kind() == reflect.Ptr {
+				if inner.IsNil() {
+					continue
+				}
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				var wrote bool
+				dst, wrote = appendNestedStruct(dst, inner, depth, start, first)
+				first = first && !wrote
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if strings.Contains(opts, ",omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		if over(dst, start) {
+			break
+		}
+		if !first {
+			dst = append(dst, ',')
+		}
+		first = false
+		dst = appendJSONString(dst, name)
+		dst = append(dst, ':')
+		if secret == "mask" {
+			dst = appendJSONString(dst, LOG_MASK)
+			continue
+		}
+		dst = appendNestedValue(dst, fv, depth+1, start)
+	}
+	return dst, !first
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// flattened reports whether fmt would lose the structure of v: maps and
+// structs, and slices or arrays of them, unless v has a String or Error
+// method. Flat lists keep their [a b c] text.
+func flattened(v interface{}) bool {
+	switch v.(type) {
+	case fmt.Stringer, error:
+		return false
+	}
+	if !isNested(v) {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		elem := t.Elem()
+		for elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+		return elem.Kind() == reflect.Interface || isNested(reflect.Zero(elem).Interface())
+	}
+	return true
+}
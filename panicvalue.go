@@ -0,0 +1,155 @@
+package elog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	LOG_FIELD_PANIC_TYPE    = "panic_type"
+	LOG_FIELD_PANIC_MESSAGE = "panic_message"
+	LOG_FIELD_PANIC_VALUE   = "panic_value"
+	LOG_FIELD_PANIC_CAUSES  = "panic_causes"
+	LOG_FIELD_STACK         = "stack"
+)
+
+// panicFields describes the value given alone to Fatal or Panic when it is
+// an error or a struct, so crash aggregation can group records by type
+// instead of by formatted text: its type, the message of an error, the
+// exported struct fields with their json and elog tags applied, the types
+// of the errors it wraps and the stack. Other values give nil.
+func panicFields(args []interface{}) Fields {
+	if len(args) != 1 || args[0] == nil {
+		return nil
+	}
+	v := args[0]
+	err, isError := v.(error)
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr && !value.IsNil() {
+		value = value.Elem()
+	}
+	isStruct := value.Kind() == reflect.Struct
+	if !isError && !isStruct {
+		return nil
+	}
+	fields := Fields{LOG_FIELD_PANIC_TYPE: fmt.Sprintf("%T", v), LOG_FIELD_STACK: currentStack()}
+	if isError {
+		fields[LOG_FIELD_PANIC_MESSAGE] = err.Error()
+		var causes []string
+		for cause := unwrapError(err); cause != nil && len(causes) < 16; cause = unwrapError(cause) {
+			causes = append(causes, fmt.Sprintf("%T", cause))
+		}
+		if len(causes) > 0 {
+			fields[LOG_FIELD_PANIC_CAUSES] = strings.Join(causes, " ")
+		}
+	}
+	if isStruct {
+		if exported := structFields(value); exported != nil {
+			fields[LOG_FIELD_PANIC_VALUE] = exported
+		}
+	}
+	return fields
+}
+
+// unwrapError follows the Unwrap convention of Go 1.13 errors and the
+// Cause convention of github.com/pkg/errors.
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	}
+	return nil
+}
+
+// structFields gives the fields of struct value as appendNestedStruct
+// encodes them, elog:"omit" and elog:"mask" included, or nil for none; a
+// value over LOG_FIELD_MAX_BYTES gives its truncated text.
+func structFields(value reflect.Value) interface{} {
+	data := appendNestedValue(nil, value, 0, 0)
+	if over(data, 0) {
+		return cutNested(data)
+	}
+	fields := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return string(data)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// panicMessage is the message of Fatal and Panic: a struct given alone,
+// other than an error or a Stringer, is written as its JSON so that fields
+// tagged elog:"omit" or elog:"mask" do not leak through fmt.
+func panicMessage(args []interface{}) string {
+	if len(args) == 1 && flattened(args[0]) {
+		t := reflect.TypeOf(args[0])
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return nestedText(args[0])
+		}
+	}
+	return sprintln(args...)
+}
+
+// currentStack returns the stack of the calling goroutine without the
+// frames of this package.
+func currentStack() string {
+	buf := make([]byte, 16*1024)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	kept := lines[:1]
+	for i := 1; i+1 < len(lines); i += 2 {
+		if !strings.HasPrefix(lines[i], elogPackage+".") {
+			kept = append(kept, lines[i], lines[i+1])
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func (el *EasyLogger) outputValue(level int, args ...interface{}) {
+	if !el.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(el.depth)
+	if !el.callerEnabled(level, pc) {
+		return
+	}
+	el.record(&Record{Level: level, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: panicMessage(args), Fields: panicFields(args)})
+}
+
+func (e *Entry) outputValue(level int, args ...interface{}) {
+	if !e.logger.enabled(level) {
+		return
+	}
+	file, line, pc := getCaller(LOG_DEPTH_HANDLER)
+	if !e.logger.callerEnabled(level, pc) {
+		return
+	}
+	fields := e.fields.clone()
+	for k, v := range panicFields(args) {
+		if fields == nil {
+			fields = Fields{}
+		}
+		fields[k] = v
+	}
+	e.logger.record(&Record{Level: level, Name: e.name, Time: e.now(), File: file, Line: line, PC: pc, Message: panicMessage(args), Fields: fields, Context: e.ctx})
+}
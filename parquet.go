@@ -0,0 +1,481 @@
+package elog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parquet column types.
+const (
+	LOG_PARQUET_STRING    = iota // BYTE_ARRAY, UTF8
+	LOG_PARQUET_INT64            // INT64
+	LOG_PARQUET_DOUBLE           // DOUBLE
+	LOG_PARQUET_BOOL             // BOOLEAN
+	LOG_PARQUET_TIMESTAMP        // INT64, TIMESTAMP_MILLIS
+	LOG_PARQUET_JSON             // BYTE_ARRAY, JSON
+)
+
+// ParquetColumn maps a part of the record to a column. Source is one of
+// time, level, logger, file, line, msg, fields (all fields as JSON) and
+// fields.<key>. Values that do not convert to Type are stored as null.
+type ParquetColumn struct {
+	Name   string
+	Source string
+	Type   int
+}
+
+var DefaultParquetColumns = []ParquetColumn{
+	{"time", "time", LOG_PARQUET_TIMESTAMP},
+	{"level", "level", LOG_PARQUET_STRING},
+	{"logger", "logger", LOG_PARQUET_STRING},
+	{"file", "file", LOG_PARQUET_STRING},
+	{"line", "line", LOG_PARQUET_INT64},
+	{"msg", "msg", LOG_PARQUET_STRING},
+	{"fields", "fields", LOG_PARQUET_JSON},
+}
+
+// ParquetConfig configures ParquetWriter.
+type ParquetConfig struct {
+	// Columns is the schema of the file, DefaultParquetColumns when empty.
+	// All columns are optional.
+	Columns []ParquetColumn
+	// RowGroupSize is the number of rows buffered per row group, 100000 by
+	// default.
+	RowGroupSize int
+	// Compression is "gzip" or empty for none.
+	Compression string
+}
+
+// ParquetWriter writes records as a Parquet file, so that logs can be
+// queried with DuckDB, Athena and the like. Pages are PLAIN encoded, one
+// page per column chunk. Close writes the footer.
+type ParquetWriter struct {
+	w         io.Writer
+	config    ParquetConfig
+	codec     Codec
+	offset    int64
+	rows      int64
+	groupRows int
+	columns   []*parquetColumn
+	groups    []parquetRowGroup
+	err       error
+}
+
+type parquetColumn struct {
+	ParquetColumn
+	defined []bool
+	values  bytes.Buffer
+	bits    []bool
+}
+
+type parquetChunk struct {
+	offset       int64
+	values       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+	size   int64
+}
+
+func NewParquetWriter(w io.Writer, config ParquetConfig) (*ParquetWriter, error) {
+	if len(config.Columns) == 0 {
+		config.Columns = DefaultParquetColumns
+	}
+	if config.RowGroupSize <= 0 {
+		config.RowGroupSize = 100000
+	}
+	pw := &ParquetWriter{w: w, config: config}
+	switch config.Compression {
+	case "":
+	case "gzip":
+		pw.codec = NewGzipCodec(-1)
+	default:
+		return nil, errors.New("elog: parquet compression " + strconv.Quote(config.Compression) + " not supported")
+	}
+	for _, c := range config.Columns {
+		if c.Name == "" || c.Type < LOG_PARQUET_STRING || c.Type > LOG_PARQUET_JSON {
+			return nil, errors.New("elog: bad parquet column " + strconv.Quote(c.Name))
+		}
+		pw.columns = append(pw.columns, &parquetColumn{ParquetColumn: c})
+	}
+	pw.write([]byte("PAR1"))
+	return pw, pw.err
+}
+
+func (pw *ParquetWriter) write(p []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(p)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+// Write adds r as a row.
+func (pw *ParquetWriter) Write(r *Record) error {
+	for _, c := range pw.columns {
+		c.add(parquetSource(r, c.Source))
+	}
+	pw.rows++
+	pw.groupRows++
+	if pw.groupRows >= pw.config.RowGroupSize {
+		pw.flushRowGroup()
+	}
+	return pw.err
+}
+
+func parquetSource(r *Record, source string) interface{} {
+	switch source {
+	case "time":
+		return r.Time
+	case "level":
+		return getLogLevelString(r.Level)
+	case "logger":
+		return r.Name
+	case "file":
+		return r.File
+	case "line":
+		return r.Line
+	case "msg":
+		return r.Message
+	case "fields":
+		if len(r.Fields) == 0 {
+			return nil
+		}
+		var buf bytes.Buffer
+		writeJSONObject(&buf, r.Fields)
+		return buf.String()
+	}
+	if strings.HasPrefix(source, "fields.") {
+		if v, ok := fieldPath(r.Fields, source[len("fields."):]); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (c *parquetColumn) add(v interface{}) {
+	if v == nil {
+		c.defined = append(c.defined, false)
+		return
+	}
+	var scratch [8]byte
+	switch c.Type {
+	case LOG_PARQUET_STRING, LOG_PARQUET_JSON:
+		var s string
+		if str, ok := v.(string); ok {
+			s = str
+		} else if c.Type == LOG_PARQUET_JSON {
+			var buf bytes.Buffer
+			writeJSONValue(&buf, v)
+			s = buf.String()
+		} else {
+			s = formatQueryValue(v)
+		}
+		binary.LittleEndian.PutUint32(scratch[:4], uint32(len(s)))
+		c.values.Write(scratch[:4])
+		c.values.WriteString(s)
+	case LOG_PARQUET_TIMESTAMP:
+		t, ok := v.(interface{ UnixNano() int64 })
+		if !ok {
+			c.defined = append(c.defined, false)
+			return
+		}
+		binary.LittleEndian.PutUint64(scratch[:], uint64(t.UnixNano()/1e6))
+		c.values.Write(scratch[:])
+	case LOG_PARQUET_INT64:
+		n, err := strconv.ParseInt(formatQueryValue(v), 10, 64)
+		if err != nil {
+			c.defined = append(c.defined, false)
+			return
+		}
+		binary.LittleEndian.PutUint64(scratch[:], uint64(n))
+		c.values.Write(scratch[:])
+	case LOG_PARQUET_DOUBLE:
+		f, err := strconv.ParseFloat(formatQueryValue(v), 64)
+		if err != nil {
+			c.defined = append(c.defined, false)
+			return
+		}
+		binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(f))
+		c.values.Write(scratch[:])
+	case LOG_PARQUET_BOOL:
+		b, err := strconv.ParseBool(formatQueryValue(v))
+		if err != nil {
+			c.defined = append(c.defined, false)
+			return
+		}
+		c.bits = append(c.bits, b)
+	}
+	c.defined = append(c.defined, true)
+}
+
+// page returns the data page of the column: the definition levels, RLE
+// bit-packed with a length prefix, then the PLAIN values.
+func (c *parquetColumn) page() []byte {
+	var page bytes.Buffer
+	levels := packBits(c.defined)
+	var header [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(header[:], uint64(len(levels))<<1|1)
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(n+len(levels)))
+	page.Write(length[:])
+	page.Write(header[:n])
+	page.Write(levels)
+	if c.Type == LOG_PARQUET_BOOL {
+		page.Write(packBits(c.bits))
+	} else {
+		page.Write(c.values.Bytes())
+	}
+	return page.Bytes()
+}
+
+// packBits packs bools LSB first, padded to whole groups of 8.
+func packBits(bits []bool) []byte {
+	packed := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			packed[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return packed
+}
+
+func (pw *ParquetWriter) flushRowGroup() {
+	if pw.groupRows == 0 {
+		return
+	}
+	group := parquetRowGroup{rows: int64(pw.groupRows)}
+	for _, c := range pw.columns {
+		data := c.page()
+		uncompressed := len(data)
+		if pw.codec != nil {
+			var compressed bytes.Buffer
+			if err := pw.codec.Compress(&compressed, data); err != nil && pw.err == nil {
+				pw.err = err
+			}
+			data = compressed.Bytes()
+		}
+		var header thriftWriter
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(uncompressed))
+		header.i32(3, int32(len(data)))
+		header.beginStruct(5)
+		header.i32(1, int32(pw.groupRows))
+		header.i32(2, 0) // PLAIN
+		header.i32(3, 3) // RLE
+		header.i32(4, 3)
+		header.endStruct()
+		header.stop()
+		chunk := parquetChunk{offset: pw.offset, values: int64(pw.groupRows),
+			uncompressed: int64(header.buf.Len() + uncompressed), compressed: int64(header.buf.Len() + len(data))}
+		pw.write(header.buf.Bytes())
+		pw.write(data)
+		group.chunks = append(group.chunks, chunk)
+		group.size += chunk.uncompressed
+		c.defined = c.defined[:0]
+		c.values.Reset()
+		c.bits = c.bits[:0]
+	}
+	pw.groups = append(pw.groups, group)
+	pw.groupRows = 0
+}
+
+func (c *parquetColumn) physicalType() int32 {
+	switch c.Type {
+	case LOG_PARQUET_INT64, LOG_PARQUET_TIMESTAMP:
+		return 2
+	case LOG_PARQUET_DOUBLE:
+		return 5
+	case LOG_PARQUET_BOOL:
+		return 0
+	}
+	return 6 // BYTE_ARRAY
+}
+
+// Close writes the last row group and the footer. It does not close the
+// underlying writer.
+func (pw *ParquetWriter) Close() error {
+	pw.flushRowGroup()
+	var meta thriftWriter
+	meta.i32(1, 1)
+	meta.beginList(2, thriftStruct, len(pw.columns)+1)
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.stop()
+	for _, c := range pw.columns {
+		meta.i32(1, c.physicalType())
+		meta.i32(3, 1) // OPTIONAL
+		meta.binary(4, c.Name)
+		switch c.Type {
+		case LOG_PARQUET_STRING:
+			meta.i32(6, 0) // UTF8
+		case LOG_PARQUET_TIMESTAMP:
+			meta.i32(6, 9) // TIMESTAMP_MILLIS
+		case LOG_PARQUET_JSON:
+			meta.i32(6, 19) // JSON
+		}
+		meta.stop()
+	}
+	meta.endList()
+	meta.i64(3, pw.rows)
+	meta.beginList(4, thriftStruct, len(pw.groups))
+	codec := int32(0)
+	if pw.codec != nil {
+		codec = 2 // GZIP
+	}
+	for _, group := range pw.groups {
+		meta.beginList(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			c := pw.columns[i]
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, c.physicalType())
+			meta.beginList(2, thriftI32, 2)
+			meta.zigzag(0) // PLAIN
+			meta.zigzag(3) // RLE
+			meta.endList()
+			meta.beginList(3, thriftBinary, 1)
+			meta.rawBinary(c.Name)
+			meta.endList()
+			meta.i32(4, codec)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.uncompressed)
+			meta.i64(7, chunk.compressed)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.stop()
+		}
+		meta.endList()
+		meta.i64(2, group.size)
+		meta.i64(3, group.rows)
+		meta.stop()
+	}
+	meta.endList()
+	meta.binary(6, "elog")
+	meta.stop()
+	pw.write(meta.buf.Bytes())
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	pw.write(length[:])
+	pw.write([]byte("PAR1"))
+	return pw.err
+}
+
+// ExportParquet writes the records of the log files at paths matching q,
+// nil for all, to w as Parquet, merged in time order as by MergeFiles. It
+// returns the number of rows written.
+func ExportParquet(w io.Writer, paths []string, q *Query, config ParquetConfig) (int64, error) {
+	pw, err := NewParquetWriter(w, config)
+	if err != nil {
+		return 0, err
+	}
+	if err := MergeFiles(paths, q, pw.Write); err != nil {
+		return pw.rows, err
+	}
+	return pw.rows, pw.Close()
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata with the Thrift compact
+// protocol.
+type thriftWriter struct {
+	buf   bytes.Buffer
+	last  []int16
+	field int16
+}
+
+func (tw *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	tw.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (tw *thriftWriter) zigzag(v int64) {
+	tw.varint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (tw *thriftWriter) header(id int16, typ byte) {
+	if delta := id - tw.field; delta > 0 && delta <= 15 {
+		tw.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		tw.buf.WriteByte(typ)
+		tw.zigzag(int64(id))
+	}
+	tw.field = id
+}
+
+func (tw *thriftWriter) i32(id int16, v int32) {
+	tw.header(id, thriftI32)
+	tw.zigzag(int64(v))
+}
+
+func (tw *thriftWriter) i64(id int16, v int64) {
+	tw.header(id, thriftI64)
+	tw.zigzag(v)
+}
+
+func (tw *thriftWriter) binary(id int16, s string) {
+	tw.header(id, thriftBinary)
+	tw.rawBinary(s)
+}
+
+func (tw *thriftWriter) rawBinary(s string) {
+	tw.varint(uint64(len(s)))
+	tw.buf.WriteString(s)
+}
+
+// beginStruct starts a struct field; its fields follow, ended by
+// endStruct.
+func (tw *thriftWriter) beginStruct(id int16) {
+	tw.header(id, thriftStruct)
+	tw.last = append(tw.last, tw.field)
+	tw.field = 0
+}
+
+func (tw *thriftWriter) endStruct() {
+	tw.buf.WriteByte(0)
+	tw.field = tw.last[len(tw.last)-1]
+	tw.last = tw.last[:len(tw.last)-1]
+}
+
+// beginList starts a list field, ended by endList. Struct elements are
+// each ended by stop, other elements are written raw.
+func (tw *thriftWriter) beginList(id int16, elem byte, size int) {
+	tw.header(id, thriftList)
+	if size < 15 {
+		tw.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		tw.buf.WriteByte(0xf0 | elem)
+		tw.varint(uint64(size))
+	}
+	tw.last = append(tw.last, tw.field)
+	tw.field = 0
+}
+
+func (tw *thriftWriter) endList() {
+	tw.field = tw.last[len(tw.last)-1]
+	tw.last = tw.last[:len(tw.last)-1]
+}
+
+// stop ends a struct element of a list, or the top-level struct.
+func (tw *thriftWriter) stop() {
+	tw.buf.WriteByte(0)
+	tw.field = 0
+}
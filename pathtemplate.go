@@ -0,0 +1,69 @@
+package elog
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SetPathTemplate names the log files from a template relative to the
+// handler path, e.g. "{host}/{app}-{date}.log", so replicas sharing a volume
+// write to their own files. Placeholders are {host}, {app}, {pid} and
+// {date}; missing directories are created on open.
+func (efh *EasyFileHandler) SetPathTemplate(tmpl string) error {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	values := map[string]string{
+		"host": safePathElement(host),
+		"app":  safePathElement(getAppName()),
+		"pid":  strconv.Itoa(os.Getpid()),
+	}
+	var expanded strings.Builder
+	rest := tmpl
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			expanded.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return errors.New("elog: unterminated placeholder in " + strconv.Quote(tmpl))
+		}
+		name := rest[start+1 : start+end]
+		expanded.WriteString(rest[:start])
+		if name == "date" {
+			// expanded again for every file
+			expanded.WriteString("{date}")
+		} else if value, ok := values[name]; ok {
+			expanded.WriteString(value)
+		} else {
+			return errors.New("elog: unknown placeholder {" + name + "} in " + strconv.Quote(tmpl))
+		}
+		rest = rest[start+end+1:]
+	}
+	for _, elem := range strings.Split(expanded.String(), "/") {
+		if elem == ".." {
+			return errors.New("elog: path template leaves the log path: " + strconv.Quote(tmpl))
+		}
+	}
+	efh.template = expanded.String()
+	return nil
+}
+
+// safePathElement keeps a host or app name from adding directories.
+func safePathElement(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+package elog
+
+// Clone returns a copy of the record with its own Fields map, that can be
+// modified and emitted without affecting r.
+func (r *Record) Clone() *Record {
+	c := *r
+	c.Fields = r.Fields.clone()
+	return &c
+}
+
+// Emit writes a record built elsewhere, e.g. translated from another
+// logging library or forwarded by a hook. Level filtering applies; a zero
+// Time is set to now. The logger owns r afterwards.
+func (el *EasyLogger) Emit(r *Record) {
+	if !el.enabled(r.Level) {
+		return
+	}
+	if r.PC != 0 && !el.callerEnabled(r.Level, r.PC) {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = el.clock.Now()
+	}
+	el.record(r)
+}
+
+func Emit(r *Record) {
+	logger.Emit(r)
+}
+
+// Hook sees every record before it is written and may modify it; returning
+// false drops the record. Hooks run outside the logger lock, in the logging
+// goroutine, so they may log or Emit cloned records to other loggers.
+type Hook func(r *Record) bool
+
+func (el *EasyLogger) AddHook(hook Hook) {
+	el.lock()
+	defer el.unlock()
+	old, _ := el.hooks.Load().([]Hook)
+	hooks := make([]Hook, len(old), len(old)+1)
+	copy(hooks, old)
+	el.hooks.Store(append(hooks, hook))
+}
+
+func AddHook(hook Hook) {
+	logger.AddHook(hook)
+}
+
+func (el *EasyLogger) runHooks(r *Record) bool {
+	hooks, _ := el.hooks.Load().([]Hook)
+	for _, hook := range hooks {
+		if !hook(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package elog
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+type packageLevel struct {
+	prefix string
+	level  int
+}
+
+// packageLevels holds the per-package levels of a config, longest prefix
+// first, and caches the level resolved for each call site.
+type packageLevels struct {
+	levels []packageLevel
+	min    int
+	cache  sync.Map // pc -> int, -1 when no prefix matches
+}
+
+// SetPackageLevel sets the minimum level of the records logged from the
+// packages under the import path prefix, e.g. "github.com/acme/app/cache",
+// overriding the logger level in both directions. An empty level removes
+// the prefix.
+func (el *EasyLogger) SetPackageLevel(prefix string, level string) {
+	el.updateConfig(func(c *loggerConfig) {
+		var levels []packageLevel
+		if c.packages != nil {
+			for _, pl := range c.packages.levels {
+				if pl.prefix != prefix {
+					levels = append(levels, pl)
+				}
+			}
+		}
+		if level != "" {
+			levels = append(levels, packageLevel{prefix: prefix, level: getLogLevelInt(level)})
+		}
+		if len(levels) == 0 {
+			c.packages = nil
+			return
+		}
+		sort.Slice(levels, func(i, j int) bool {
+			return len(levels[i].prefix) > len(levels[j].prefix)
+		})
+		packages := &packageLevels{levels: levels, min: LOG_LEVEL_NONE}
+		for _, pl := range levels {
+			if pl.level < packages.min {
+				packages.min = pl.level
+			}
+		}
+		c.packages = packages
+	})
+}
+
+func SetPackageLevel(prefix string, level string) {
+	logger.SetPackageLevel(prefix, level)
+}
+
+// levelAt returns the level set for the package of pc, or -1.
+func (pl *packageLevels) levelAt(pc uintptr) int {
+	if level, ok := pl.cache.Load(pc); ok {
+		return level.(int)
+	}
+	level := -1
+	pkg, _ := callerFunction(pc)
+	for _, p := range pl.levels {
+		if pkg == p.prefix || strings.HasPrefix(pkg, p.prefix+"/") {
+			level = p.level
+			break
+		}
+	}
+	pl.cache.Store(pc, level)
+	return level
+}
+
+// callerEnabled decides, once the call site is known, for records that
+// passed enabled.
+func (el *EasyLogger) callerEnabled(level int, pc uintptr) bool {
+	config := el.getConfig()
+	if config.packages == nil {
+		return true
+	}
+	if pkgLevel := config.packages.levelAt(pc); pkgLevel >= 0 {
+		return level >= pkgLevel
+	}
+	return level >= config.level
+}
@@ -0,0 +1,79 @@
+package elog
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	LOG_PRESET_MAX_FILE_SIZE = 100 * 1024 * 1024
+)
+
+// Config is a complete logger setup as assembled by the presets; change
+// its fields or append options before calling New, e.g.
+// elog.ProductionFileConfig("/var/log/app").New().
+type Config struct {
+	Level       string
+	LogToStderr bool
+	FlushTime   int
+	Handler     io.Writer
+	Options     []Option
+}
+
+func (c Config) New() *EasyLogger {
+	return NewEasyLogger(c.Level, c.LogToStderr, c.FlushTime, c.Handler, c.Options...)
+}
+
+// DevelopmentConfig logs everything from DEBUG on to stderr with
+// DevEncoder, format checks and a flush after every record.
+func DevelopmentConfig() Config {
+	return Config{
+		Level:     "DEBUG",
+		FlushTime: 1,
+		Handler:   os.Stderr,
+		Options:   []Option{WithDevelopment(), WithSynchronous()},
+	}
+}
+
+// ProductionFileConfig logs INFO and above as JSON to daily files in dir,
+// rotated at LOG_PRESET_MAX_FILE_SIZE, collapses repeated records, batches
+// flushes during log storms and keeps FATAL records in dir/crash.log. dir
+// is created if missing.
+func ProductionFileConfig(dir string) Config {
+	os.MkdirAll(dir, 0755)
+	handler := NewEasyFileHandler(dir, LOG_MAX_BUFFER_SIZE)
+	handler.SetMaxSize(LOG_PRESET_MAX_FILE_SIZE)
+	return Config{
+		Level:     "INFO",
+		FlushTime: 3,
+		Handler:   handler,
+		Options: []Option{
+			WithEncoder(JSONEncoder{}),
+			WithDedup(time.Minute),
+			WithBurstMode(10000, 5),
+			WithCrashFile(filepath.Join(dir, "crash.log")),
+			WithRecentErrors(100),
+		},
+	}
+}
+
+// KubernetesConfig logs JSON lines to stdout for the node's log collector,
+// at the level of ELOG_LEVEL or INFO, collapsing repeated records.
+func KubernetesConfig() Config {
+	level := "INFO"
+	if env, ok := os.LookupEnv(LOG_ENV_LEVEL); ok {
+		level = env
+	}
+	return Config{
+		Level:     level,
+		FlushTime: 1,
+		Handler:   os.Stdout,
+		Options: []Option{
+			WithEncoder(JSONEncoder{}),
+			WithDedup(10 * time.Second),
+			WithRecentErrors(100),
+		},
+	}
+}
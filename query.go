@@ -0,0 +1,267 @@
+package elog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query is a filter over records, parsed by ParseQuery.
+type Query struct {
+	// any of the branches matches when all of its conditions do
+	branches [][]queryCondition
+}
+
+type queryCondition struct {
+	key   string
+	op    string
+	value string
+	level int
+	time  time.Time
+}
+
+var queryOperators = []string{">=", "<=", "!=", ">", "<", "=", "~"}
+
+// ParseQuery parses a filter such as
+//
+//	level>=WARN AND fields.user_id=42
+//	logger=db AND msg~timeout OR fields.retry>3
+//
+// Conditions are joined by AND, which binds tighter than OR; there are no
+// parentheses. Keys are level, time, logger, file, line, msg and fields.<key>,
+// where <key> may be a dotted path into nested objects. Operators are =,
+// !=, <, <=, >, >= and ~ (contains). Levels compare by severity, times as
+// RFC 3339, other values numerically when both sides are numbers and as
+// strings otherwise. Values containing spaces are double-quoted.
+func ParseQuery(expr string) (*Query, error) {
+	words, err := queryWords(expr)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	var branch []queryCondition
+	expectCondition := true
+	for _, word := range words {
+		switch {
+		case !expectCondition && strings.EqualFold(word, "AND"):
+			expectCondition = true
+		case !expectCondition && strings.EqualFold(word, "OR"):
+			q.branches = append(q.branches, branch)
+			branch = nil
+			expectCondition = true
+		case expectCondition:
+			c, err := parseQueryCondition(word)
+			if err != nil {
+				return nil, err
+			}
+			branch = append(branch, c)
+			expectCondition = false
+		default:
+			return nil, errors.New("elog: query: expected AND or OR before " + strconv.Quote(word))
+		}
+	}
+	if expectCondition && len(words) > 0 {
+		return nil, errors.New("elog: query: condition expected at end")
+	}
+	if len(branch) > 0 {
+		q.branches = append(q.branches, branch)
+	}
+	return q, nil
+}
+
+// queryWords splits expr on spaces outside double quotes.
+func queryWords(expr string) ([]string, error) {
+	var words []string
+	var word []byte
+	quoted := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(expr):
+			i++
+			word = append(word, expr[i])
+		case c == '"':
+			quoted = !quoted
+		case (c == ' ' || c == '\t') && !quoted:
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+		default:
+			word = append(word, c)
+		}
+	}
+	if quoted {
+		return nil, errors.New("elog: query: unterminated quote")
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words, nil
+}
+
+func parseQueryCondition(word string) (queryCondition, error) {
+	at, op := -1, ""
+	for _, candidate := range queryOperators {
+		if i := strings.Index(word, candidate); i > 0 && (at < 0 || i < at) {
+			at, op = i, candidate
+		}
+	}
+	if at < 0 {
+		return queryCondition{}, errors.New("elog: query: no operator in " + strconv.Quote(word))
+	}
+	c := queryCondition{key: word[:at], op: op, value: word[at+len(op):]}
+	switch {
+	case c.key == "level":
+		if _, ok := currentLevels().values[strings.ToUpper(c.value)]; !ok {
+			return c, errors.New("elog: query: unknown level " + strconv.Quote(c.value))
+		}
+		c.level = getLogLevelInt(strings.ToUpper(c.value))
+	case c.key == "time":
+		t, err := time.Parse(time.RFC3339Nano, c.value)
+		if err != nil {
+			return c, errors.New("elog: query: bad time " + strconv.Quote(c.value))
+		}
+		c.time = t
+	case c.key == "logger" || c.key == "file" || c.key == "line" || c.key == "msg":
+	case strings.HasPrefix(c.key, "fields.") && len(c.key) > len("fields."):
+	default:
+		return c, errors.New("elog: query: unknown key " + strconv.Quote(c.key))
+	}
+	return c, nil
+}
+
+// Match reports whether r passes the query; an empty query matches every
+// record.
+func (q *Query) Match(r *Record) bool {
+	if len(q.branches) == 0 {
+		return true
+	}
+	for _, branch := range q.branches {
+		matched := true
+		for _, c := range branch {
+			if !c.match(r) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Since returns the earliest time a matching record can have, taken from
+// time>= and time> conditions present in every branch, so that readers can
+// seek past older records; zero when unbounded.
+func (q *Query) Since() time.Time {
+	var since time.Time
+	for i, branch := range q.branches {
+		var bound time.Time
+		for _, c := range branch {
+			if c.key == "time" && (c.op == ">=" || c.op == ">" || c.op == "=") && c.time.After(bound) {
+				bound = c.time
+			}
+		}
+		if bound.IsZero() {
+			return time.Time{}
+		}
+		if i == 0 || bound.Before(since) {
+			since = bound
+		}
+	}
+	return since
+}
+
+func (c *queryCondition) match(r *Record) bool {
+	switch c.key {
+	case "level":
+		return compareOrdered(c.op, r.Level-c.level)
+	case "time":
+		switch {
+		case r.Time.Before(c.time):
+			return compareOrdered(c.op, -1)
+		case r.Time.After(c.time):
+			return compareOrdered(c.op, 1)
+		}
+		return compareOrdered(c.op, 0)
+	case "logger":
+		return c.compare(r.Name)
+	case "file":
+		return c.compare(r.File)
+	case "line":
+		return c.compare(strconv.Itoa(r.Line))
+	case "msg":
+		return c.compare(r.Message)
+	}
+	v, ok := fieldPath(r.Fields, c.key[len("fields."):])
+	if !ok {
+		return c.op == "!="
+	}
+	return c.compare(formatQueryValue(v))
+}
+
+func (c *queryCondition) compare(s string) bool {
+	if c.op == "~" {
+		return strings.Contains(s, c.value)
+	}
+	a, aerr := strconv.ParseFloat(s, 64)
+	b, berr := strconv.ParseFloat(c.value, 64)
+	if aerr == nil && berr == nil {
+		switch {
+		case a < b:
+			return compareOrdered(c.op, -1)
+		case a > b:
+			return compareOrdered(c.op, 1)
+		}
+		return compareOrdered(c.op, 0)
+	}
+	return compareOrdered(c.op, strings.Compare(s, c.value))
+}
+
+func compareOrdered(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// fieldPath looks key up in fields, following a dotted path into nested
+// objects when no field has the whole key.
+func fieldPath(fields map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := fields[key]; ok {
+		return v, true
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+		if nested, ok := fields[key[:i]].(map[string]interface{}); ok {
+			if v, ok := fieldPath(nested, key[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func formatQueryValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,220 @@
+package elog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordReader decodes the records of a log file, written by JSONEncoder
+// or TextEncoder, one at a time. Text continuation lines are joined to
+// their record; lines that are not records are skipped.
+type RecordReader struct {
+	reader  *bufio.Reader
+	pending string
+	err     error
+}
+
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{reader: bufio.NewReaderSize(r, 64*1024)}
+}
+
+func (rr *RecordReader) line() (string, error) {
+	if rr.pending != "" {
+		line := rr.pending
+		rr.pending = ""
+		return line, nil
+	}
+	if rr.err != nil {
+		return "", rr.err
+	}
+	line, err := rr.reader.ReadString('\n')
+	if err != nil {
+		rr.err = err
+		if line == "" {
+			return "", err
+		}
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// Next returns the next record, or io.EOF at the end of the input.
+func (rr *RecordReader) Next() (*Record, error) {
+	for {
+		line, err := rr.line()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(line, "{") {
+			if r, err := DecodeJSONRecord([]byte(line)); err == nil {
+				return r, nil
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "[") {
+			continue
+		}
+		for {
+			next, err := rr.line()
+			if err != nil {
+				break
+			}
+			if !strings.HasPrefix(next, "\t") {
+				rr.pending = next
+				break
+			}
+			line += "\n" + next[1:]
+		}
+		if r, err := ParseTextRecord(line); err == nil {
+			return r, nil
+		}
+	}
+}
+
+// ParseTextRecord parses a record written by TextEncoder, continuation
+// lines joined with "\n". The trailing key=value pairs become string
+// fields; the time is read in the local time zone.
+func ParseTextRecord(text string) (*Record, error) {
+	r := &Record{}
+	var tokens []string
+	rest := text
+	for len(tokens) < 4 && strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			break
+		}
+		tokens = append(tokens, rest[1:end])
+		rest = rest[end+1:]
+		if strings.HasPrefix(tokens[len(tokens)-1], "file:") {
+			break
+		}
+	}
+	if len(tokens) < 3 || !strings.HasPrefix(tokens[len(tokens)-1], "file:") {
+		return nil, errors.New("elog: not a text record")
+	}
+	level, ok := currentLevels().values[tokens[0]]
+	if !ok {
+		return nil, errors.New("elog: unknown level " + strconv.Quote(tokens[0]))
+	}
+	r.Level = level
+	if len(tokens) == 4 {
+		r.Name = tokens[1]
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", tokens[len(tokens)-2], time.Local)
+	if err != nil {
+		return nil, err
+	}
+	r.Time = t
+	caller := tokens[len(tokens)-1]
+	if i := strings.Index(caller, " line:"); i >= 0 {
+		r.File = caller[len("file:"):i]
+		r.Line, _ = strconv.Atoi(caller[i+len(" line:"):])
+	}
+	r.Message, r.Fields = splitTextFields(strings.TrimPrefix(rest, " "))
+	return r, nil
+}
+
+// splitTextFields separates the trailing key=value pairs of a text record
+// from its message.
+func splitTextFields(text string) (string, Fields) {
+	type token struct {
+		start      int
+		key, value string
+		field      bool
+	}
+	var tokens []token
+	for i := 0; i < len(text); {
+		if text[i] == ' ' || text[i] == '\n' {
+			i++
+			continue
+		}
+		tok := token{start: i}
+		j := i
+		for j < len(text) && !strings.ContainsRune(" \n=\"", rune(text[j])) {
+			j++
+		}
+		end := j
+		if j > i && j < len(text) && text[j] == '=' {
+			if j+1 < len(text) && text[j+1] == '"' {
+				if k := quotedEnd(text, j+1); k > 0 {
+					if value, err := strconv.Unquote(text[j+1 : k]); err == nil {
+						tok.key, tok.value, tok.field, end = text[i:j], value, true, k
+					}
+				}
+			} else {
+				k := j + 1
+				for k < len(text) && !strings.ContainsRune(" \n=\"", rune(text[k])) {
+					k++
+				}
+				tok.key, tok.value, tok.field, end = text[i:j], text[j+1:k], true, k
+			}
+		}
+		if !tok.field || end < len(text) && text[end] != ' ' && text[end] != '\n' {
+			tok.field = false
+			for end < len(text) && text[end] != ' ' && text[end] != '\n' {
+				end++
+			}
+		}
+		tokens = append(tokens, tok)
+		i = end
+	}
+	first := len(tokens)
+	for first > 0 && tokens[first-1].field {
+		first--
+	}
+	if first == len(tokens) {
+		return text, nil
+	}
+	fields := Fields{}
+	for _, tok := range tokens[first:] {
+		fields[tok.key] = tok.value
+	}
+	return strings.TrimRight(text[:tokens[first].start], " "), fields
+}
+
+// quotedEnd returns the index after the Go quoted string starting at i.
+func quotedEnd(text string, i int) int {
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// QueryFile streams the records of the log file at path matching q to fn,
+// starting at the offset SeekTime gives for q.Since() when the file has an
+// index. fn returning an error stops the query with that error.
+func QueryFile(path string, q *Query, fn func(r *Record) error) error {
+	file, err := OpenLogAt(path, q.Since())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return QueryRecords(file, q, fn)
+}
+
+// QueryRecords streams the records read from r matching q to fn.
+func QueryRecords(r io.Reader, q *Query, fn func(r *Record) error) error {
+	rr := NewRecordReader(r)
+	for {
+		rec, err := rr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if q.Match(rec) {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package elog
+
+import (
+	"sync"
+	"time"
+)
+
+// RecentError is an ERROR or FATAL message kept by WithRecentErrors, with
+// the number of times it was logged.
+type RecentError struct {
+	Level   string
+	Message string
+	File    string
+	Line    int
+	First   time.Time
+	Last    time.Time
+	Count   int64
+}
+
+type recentErrors struct {
+	mutex  sync.Mutex
+	size   int
+	errors []RecentError // oldest first
+}
+
+// WithRecentErrors keeps the last n distinct ERROR and FATAL messages in
+// memory for RecentErrors, repeated messages from the same call site only
+// update the count and time.
+func WithRecentErrors(n int) Option {
+	return func(el *EasyLogger) {
+		el.recent = &recentErrors{size: n}
+	}
+}
+
+func (re *recentErrors) add(r *Record) {
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+	for i := len(re.errors) - 1; i >= 0; i-- {
+		e := &re.errors[i]
+		if e.Message == r.Message && e.File == r.File && e.Line == r.Line {
+			e.Count++
+			e.Last = r.Time
+			e.Level = getLogLevelString(r.Level)
+			moved := *e
+			copy(re.errors[i:], re.errors[i+1:])
+			re.errors[len(re.errors)-1] = moved
+			return
+		}
+	}
+	if len(re.errors) >= re.size {
+		copy(re.errors, re.errors[1:])
+		re.errors = re.errors[:len(re.errors)-1]
+	}
+	re.errors = append(re.errors, RecentError{
+		Level:   getLogLevelString(r.Level),
+		Message: r.Message,
+		File:    r.File,
+		Line:    r.Line,
+		First:   r.Time,
+		Last:    r.Time,
+		Count:   1,
+	})
+}
+
+// RecentErrors returns the errors kept by WithRecentErrors, most recent
+// first.
+func (el *EasyLogger) RecentErrors() []RecentError {
+	if el.recent == nil {
+		return nil
+	}
+	el.recent.mutex.Lock()
+	defer el.recent.mutex.Unlock()
+	errors := make([]RecentError, len(el.recent.errors))
+	for i, e := range el.recent.errors {
+		errors[len(errors)-1-i] = e
+	}
+	return errors
+}
+
+func RecentErrors() []RecentError {
+	return logger.RecentErrors()
+}
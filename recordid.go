@@ -0,0 +1,110 @@
+package elog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const LOG_FIELD_ID = "id"
+
+// IDGenerator returns a unique ID for a record logged at t.
+type IDGenerator func(t time.Time) string
+
+// WithRecordID stamps every record with a unique ID from gen, a ULID
+// generator when nil, in the id field, so a record can be deduplicated
+// downstream, shipped exactly once or referred to from other systems.
+func WithRecordID(gen IDGenerator) Option {
+	return func(el *EasyLogger) {
+		if gen == nil {
+			gen = NewULIDGenerator()
+		}
+		el.recordID = gen
+	}
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULIDGenerator returns a generator of ULIDs: 26 characters that sort
+// in time order, monotonic within a millisecond.
+func NewULIDGenerator() IDGenerator {
+	var mutex sync.Mutex
+	var lastMs uint64
+	var entropy [10]byte
+	return func(t time.Time) string {
+		ms := uint64(t.UnixNano() / int64(time.Millisecond))
+		mutex.Lock()
+		switch {
+		case ms > lastMs:
+			rand.Read(entropy[:])
+		case increment(entropy[:]):
+			ms = lastMs
+		default:
+			// the entropy of the millisecond is used up
+			ms = lastMs + 1
+			rand.Read(entropy[:])
+		}
+		lastMs = ms
+		var id [16]byte
+		for i := 0; i < 6; i++ {
+			id[i] = byte(ms >> uint(40-8*i))
+		}
+		copy(id[6:], entropy[:])
+		mutex.Unlock()
+		return encodeULID(id)
+	}
+}
+
+// increment adds one to the big-endian number b, reporting false on
+// overflow.
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeULID writes the 128 bits of id as 26 Crockford base32 digits, the
+// first one holding the top 3 bits.
+func encodeULID(id [16]byte) string {
+	var out [26]byte
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(id[i])
+		lo = lo<<8 | uint64(id[8+i])
+	}
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// UUIDv7 is a generator of version 7 UUIDs, time ordered to the
+// millisecond.
+func UUIDv7(t time.Time) string {
+	var id [16]byte
+	rand.Read(id[6:])
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> uint(40-8*i))
+	}
+	id[6] = id[6]&0x0f | 0x70
+	id[8] = id[8]&0x3f | 0x80
+	var out [36]byte
+	hex.Encode(out[0:8], id[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], id[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], id[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], id[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], id[10:])
+	return string(out[:])
+}
@@ -0,0 +1,114 @@
+package elog
+
+import (
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	LOG_REENTRANT_QUEUE = 256
+	LOG_REENTRANT_DEPTH = 64
+)
+
+// Handlers, flushers and closers run under the logger mutex, so a handler
+// logging about itself (a network handler reporting a lost connection, say)
+// would block on the mutex it already holds. Such records are queued instead
+// and written once the current handler call returns, before the mutex is
+// released.
+type reentrantQueue struct {
+	mutex   sync.Mutex
+	records []*Record
+	dropped int
+}
+
+// elogPackage is the import path of this package as it appears in stack
+// traces.
+var elogPackage = func() string {
+	name := runtime.FuncForPC(reflect.ValueOf(getCaller).Pointer()).Name()
+	return name[:strings.LastIndex(name, ".")]
+}()
+
+// handlerCallers are the functions of this package that call into handlers,
+// or log about elog itself, with the logger mutex held.
+var handlerCallers = func() map[string]bool {
+	pkg := elogPackage
+	callers := map[string]bool{}
+	for _, method := range []string{"writeRecord", "flushWriters", "closeWriters", "Rotate", "Sync", "writeCrash", "drainReentrant"} {
+		callers[pkg+".(*EasyLogger)."+method] = true
+	}
+	return callers
+}()
+
+// inHandlerCall reports whether the calling goroutine is inside a handler
+// call of any logger. It walks the stack, so it is only consulted while the
+// logger mutex is held.
+func inHandlerCall() bool {
+	var pcs [LOG_REENTRANT_DEPTH]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if handlerCallers[frame.Function] {
+			return true
+		}
+		if !more {
+			return false
+		}
+	}
+}
+
+func (el *EasyLogger) lock() {
+	el.mutex.Lock()
+	atomic.StoreInt32(&el.locked, 1)
+}
+
+func (el *EasyLogger) unlock() {
+	el.drainReentrant()
+	atomic.StoreInt32(&el.locked, 0)
+	el.mutex.Unlock()
+}
+
+// reentrant queues r if logging it now could deadlock: the logger mutex is
+// held and the caller is running inside a handler.
+func (el *EasyLogger) reentrant(r, stack *Record) bool {
+	if atomic.LoadInt32(&el.locked) == 0 || !inHandlerCall() {
+		return false
+	}
+	q := &el.reentrantQueue
+	q.mutex.Lock()
+	if len(q.records) >= LOG_REENTRANT_QUEUE {
+		q.dropped++
+	} else {
+		q.records = append(q.records, r)
+		if stack != nil {
+			q.records = append(q.records, stack)
+		}
+	}
+	q.mutex.Unlock()
+	return true
+}
+
+// drainReentrant writes the queued records. Records queued while draining
+// wait for the next unlock, so a handler logging on every write cannot loop.
+func (el *EasyLogger) drainReentrant() {
+	q := &el.reentrantQueue
+	q.mutex.Lock()
+	records, dropped := q.records, q.dropped
+	q.records, q.dropped = nil, 0
+	q.mutex.Unlock()
+	if atomic.LoadInt32(&el.closed) != 0 {
+		for _, r := range records {
+			TextEncoder{}.Encode(os.Stderr, r)
+		}
+		return
+	}
+	for _, r := range records {
+		el.commit(r, nil)
+	}
+	if dropped > 0 {
+		selfLogf(LOG_LEVEL_WARN, "dropped %d records logged from inside a handler", dropped)
+	}
+}
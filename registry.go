@@ -0,0 +1,74 @@
+package elog
+
+import (
+	"context"
+	"sync"
+)
+
+// registry tracks the loggers created by NewEasyLogger until they are shut
+// down, for FlushAll and CloseAll.
+var registry = struct {
+	mutex   sync.Mutex
+	loggers []*EasyLogger
+}{}
+
+func register(el *EasyLogger) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.loggers = append(registry.loggers, el)
+}
+
+func unregister(el *EasyLogger) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	for i, rl := range registry.loggers {
+		if rl == el {
+			registry.loggers = append(registry.loggers[:i], registry.loggers[i+1:]...)
+			return
+		}
+	}
+}
+
+func registeredLoggers() []*EasyLogger {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	return append([]*EasyLogger(nil), registry.loggers...)
+}
+
+// FlushAll flushes the global logger and every logger created by
+// NewEasyLogger that is not shut down yet. It returns the first error.
+func FlushAll() error {
+	var err error
+	for _, el := range registeredLoggers() {
+		if ferr := el.Flush(); err == nil {
+			err = ferr
+		}
+	}
+	if ferr := logger.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// CloseAll shuts down every logger created by NewEasyLogger, concurrently,
+// and then the global logger, which may be the self logger of the others.
+// It returns the first error, or ctx.Err() if ctx expires first.
+func CloseAll(ctx context.Context) error {
+	loggers := registeredLoggers()
+	errs := make(chan error, len(loggers))
+	for _, el := range loggers {
+		go func(el *EasyLogger) {
+			errs <- el.Shutdown(ctx)
+		}(el)
+	}
+	var err error
+	for range loggers {
+		if serr := <-errs; err == nil {
+			err = serr
+		}
+	}
+	if serr := logger.Shutdown(ctx); err == nil {
+		err = serr
+	}
+	return err
+}
@@ -0,0 +1,192 @@
+package elog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const LOG_RELAY_MAX_RECORD = 1024 * 1024
+
+// EasyRelay accepts records from the other processes of the host on a unix
+// socket and logs them through one logger, so that its files and remote
+// handlers collect the logs of the whole host. Clients send one JSON record
+// per line as written by JSONEncoder; the records keep their time, level,
+// name, caller and fields and pass the level, hooks and routes of the
+// relay's logger.
+type EasyRelay struct {
+	received  int64
+	malformed int64
+	logger    *EasyLogger
+	listener  net.Listener
+	path      string
+	mutex     sync.Mutex
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewEasyRelay listens on the unix socket path, replacing a stale socket
+// left behind by a relay that is gone.
+func NewEasyRelay(path string, el *EasyLogger) (*EasyRelay, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	relay := &EasyRelay{logger: el, listener: listener, path: path, conns: map[net.Conn]struct{}{}}
+	relay.wg.Add(1)
+	go relay.accept()
+	return relay, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.New("elog: " + path + " exists and is not a socket")
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return errors.New("elog: " + path + " is in use")
+	}
+	return os.Remove(path)
+}
+
+func (relay *EasyRelay) accept() {
+	defer relay.wg.Done()
+	for {
+		conn, err := relay.listener.Accept()
+		if err != nil {
+			if relay.isClosed() {
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			selfLogf(LOG_LEVEL_ERROR, "relay %s: %v", relay.path, err)
+			return
+		}
+		relay.mutex.Lock()
+		if relay.closed {
+			relay.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		relay.conns[conn] = struct{}{}
+		relay.wg.Add(1)
+		relay.mutex.Unlock()
+		go relay.serve(conn)
+	}
+}
+
+func (relay *EasyRelay) serve(conn net.Conn) {
+	defer relay.wg.Done()
+	defer func() {
+		relay.mutex.Lock()
+		delete(relay.conns, conn)
+		relay.mutex.Unlock()
+		conn.Close()
+	}()
+	err := relay.logger.serveRecords(conn, "relay "+relay.path, &relay.received, &relay.malformed)
+	if err != nil && !relay.isClosed() {
+		selfLogf(LOG_LEVEL_WARN, "relay %s: %v", relay.path, err)
+	}
+}
+
+func (relay *EasyRelay) isClosed() bool {
+	relay.mutex.Lock()
+	defer relay.mutex.Unlock()
+	return relay.closed
+}
+
+// ServeRecords logs the records read from r, one JSON record per line as
+// written by JSONEncoder or EasyUnixHandler, until r ends. It is the
+// receiving end of EasyUnixHandler for listeners other than EasyRelay, e.g.
+// the stdout of a child process. Malformed lines are skipped.
+func (el *EasyLogger) ServeRecords(r io.Reader) error {
+	var received, malformed int64
+	return el.serveRecords(r, "records", &received, &malformed)
+}
+
+func ServeRecords(r io.Reader) error {
+	return logger.ServeRecords(r)
+}
+
+func (el *EasyLogger) serveRecords(reader io.Reader, source string, received, malformed *int64) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 4096), LOG_RELAY_MAX_RECORD)
+	warned := false
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		r, err := DecodeJSONRecord(line)
+		if err != nil {
+			atomic.AddInt64(malformed, 1)
+			if !warned {
+				warned = true
+				selfLogf(LOG_LEVEL_WARN, "%s: malformed record: %v", source, err)
+			}
+			continue
+		}
+		atomic.AddInt64(received, 1)
+		el.relay(r)
+	}
+	return scanner.Err()
+}
+
+// relay logs a record received from another process.
+func (el *EasyLogger) relay(r *Record) {
+	if !el.enabled(r.Level) {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = el.clock.Now()
+	}
+	el.record(r)
+}
+
+// Close stops accepting records, closes the client connections and removes
+// the socket.
+func (relay *EasyRelay) Close() error {
+	relay.mutex.Lock()
+	if relay.closed {
+		relay.mutex.Unlock()
+		return nil
+	}
+	relay.closed = true
+	err := relay.listener.Close()
+	for conn := range relay.conns {
+		conn.Close()
+	}
+	relay.mutex.Unlock()
+	relay.wg.Wait()
+	return err
+}
+
+// RelayStats reports the records received by the relay and the lines that
+// could not be decoded.
+type RelayStats struct {
+	Connections int
+	Received    int64
+	Malformed   int64
+}
+
+func (relay *EasyRelay) Stats() RelayStats {
+	relay.mutex.Lock()
+	connections := len(relay.conns)
+	relay.mutex.Unlock()
+	return RelayStats{Connections: connections, Received: atomic.LoadInt64(&relay.received), Malformed: atomic.LoadInt64(&relay.malformed)}
+}
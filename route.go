@@ -0,0 +1,183 @@
+package elog
+
+import (
+	"io"
+	"strconv"
+)
+
+const (
+	LOG_FIELD_TAGS = "tags"
+)
+
+// Tag returns an entry whose records carry tags, e.g. "security" or
+// "billing", in the tags field; routes select records by them.
+func (el *EasyLogger) Tag(tags ...string) *Entry {
+	return (&Entry{logger: el}).Tag(tags...)
+}
+
+func Tag(tags ...string) *Entry {
+	return logger.Tag(tags...)
+}
+
+// Tag adds tags to those of the entry.
+func (e *Entry) Tag(tags ...string) *Entry {
+	old, _ := e.fields[LOG_FIELD_TAGS].([]string)
+	merged := make([]string, 0, len(old)+len(tags))
+	merged = append(merged, old...)
+	for _, tag := range tags {
+		if !hasTag(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	return e.WithField(LOG_FIELD_TAGS, merged)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Route sends the records carrying Tag, at Level or above, to Handler as
+// well as to the logger's handler; Stop keeps them from the latter. An
+// empty Tag matches every record, an empty Level every level. Sample, e.g.
+// 0.05, sends only that fraction of the records below WARN, evenly spread;
+// 0 sends them all. With Stop the records left out by Sample still go to
+// the logger's handler.
+type Route struct {
+	Tag     string
+	Level   string
+	Handler io.Writer
+	Stop    bool
+	Sample  float64
+}
+
+type route struct {
+	Route
+	level   int
+	stat    *handlerStat // shared by the routes of one handler
+	sampled float64      // share of a record below WARN owed to the handler
+}
+
+// WithMirror copies every WARN and above record, and fraction of the
+// others, to handler, e.g. a long-retention file getting a representative
+// sample without the full volume.
+func WithMirror(handler io.Writer, fraction float64) Option {
+	return WithRoutes(Route{Handler: handler, Sample: fraction})
+}
+
+// sample reports whether the route keeps a record below WARN.
+func (rt *route) sample() bool {
+	if rt.Sample <= 0 || rt.Sample >= 1 {
+		return true
+	}
+	rt.sampled += rt.Sample
+	if rt.sampled < 1 {
+		return false
+	}
+	rt.sampled--
+	return true
+}
+
+// WithRoutes adds routing rules, e.g.
+// Route{Tag: "security", Handler: siem} and
+// Route{Tag: "billing", Handler: auditFile, Stop: true}. A record goes to
+// every matching route's handler, once per handler.
+func WithRoutes(routes ...Route) Option {
+	return func(el *EasyLogger) {
+		for _, r := range routes {
+			level := LOG_LEVEL_DEBUG
+			if r.Level != "" {
+				level = getLogLevelInt(r.Level)
+			}
+			rt := &route{Route: r, level: level, stat: &handlerStat{}}
+			for _, other := range el.routes {
+				if other.Handler == r.Handler {
+					rt.stat = other.stat
+				}
+			}
+			el.routes = append(el.routes, rt)
+		}
+	}
+}
+
+// routeRecord writes r to the handlers of the matching routes and reports
+// whether a matching route stops it. It is called with the mutex held.
+func (el *EasyLogger) routeRecord(config *loggerConfig, r *Record, encodings *recordEncodings) bool {
+	var tags []string
+	switch t := r.Fields[LOG_FIELD_TAGS].(type) {
+	case []string:
+		tags = t
+	case string:
+		tags = []string{t}
+	}
+	stop := false
+	var written []io.Writer
+	for _, rt := range el.routes {
+		if r.Level < rt.level || rt.Tag != "" && !hasTag(tags, rt.Tag) {
+			continue
+		}
+		if r.Level < LOG_LEVEL_WARN && !rt.sample() {
+			continue
+		}
+		stop = stop || rt.Stop
+		if rt.Handler == el.writer || containsWriter(written, rt.Handler) {
+			continue
+		}
+		written = append(written, rt.Handler)
+		start := el.startTimer()
+		buf, err := encodings.get(el.encoderFor(rt.Handler, config))
+		if err != nil {
+			rt.stat.add(el, 0, err, start)
+			continue
+		}
+		n, err := el.writeEncoded(rt.Handler, r, buf.Bytes())
+		rt.stat.add(el, int64(n), err, start)
+	}
+	return stop
+}
+
+func containsWriter(writers []io.Writer, w io.Writer) bool {
+	for _, x := range writers {
+		if x == w {
+			return true
+		}
+	}
+	return false
+}
+
+// routeHandlers returns the distinct route handlers other than the
+// logger's handler.
+func (el *EasyLogger) routeHandlers() []*route {
+	var handlers []*route
+	var seen []io.Writer
+	for _, rt := range el.routes {
+		if rt.Handler == el.writer || containsWriter(seen, rt.Handler) {
+			continue
+		}
+		seen = append(seen, rt.Handler)
+		handlers = append(handlers, rt)
+	}
+	return handlers
+}
+
+func (rt *route) describe() string {
+	s := rt.Tag
+	if s == "" {
+		s = "*"
+	}
+	if rt.Route.Level != "" {
+		s += "/" + rt.Route.Level
+	}
+	s += "->" + describeHandler(rt.Handler)
+	if rt.Stop {
+		s += " stop"
+	}
+	if rt.Sample > 0 && rt.Sample < 1 {
+		s += " sample=" + strconv.FormatFloat(rt.Sample, 'g', -1, 64)
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package elog
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	LOG_SANITIZE_NONE   = 0
+	LOG_SANITIZE_STRIP  = 1
+	LOG_SANITIZE_ESCAPE = 2
+)
+
+// WithSanitize cleans messages and string field values before they are
+// encoded, so untrusted input cannot smuggle terminal escape sequences or
+// control characters into the log. LOG_SANITIZE_STRIP removes ANSI escape
+// sequences and control characters, LOG_SANITIZE_ESCAPE rewrites them as
+// visible escapes such as \x1b. Newlines and tabs are kept.
+func WithSanitize(mode int) Option {
+	return func(el *EasyLogger) {
+		el.updateConfig(func(c *loggerConfig) {
+			c.sanitize = mode
+		})
+	}
+}
+
+func sanitizeRecord(r *Record, mode int) {
+	r.Message = sanitize(r.Message, mode)
+	if len(r.Fields) == 0 {
+		return
+	}
+	fields := make(Fields, len(r.Fields))
+	for k, v := range r.Fields {
+		switch value := v.(type) {
+		case string:
+			v = sanitize(value, mode)
+		case error:
+			if s := value.Error(); needsSanitize(s) {
+				v = sanitize(s, mode)
+			}
+		}
+		fields[sanitize(k, mode)] = v
+	}
+	r.Fields = fields
+}
+
+func isControl(c rune) bool {
+	if c == '\n' || c == '\t' {
+		return false
+	}
+	return c < 0x20 || c == 0x7f || (c >= 0x80 && c <= 0x9f)
+}
+
+func needsSanitize(s string) bool {
+	for _, c := range s {
+		if isControl(c) || c == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitize(s string, mode int) string {
+	if mode == LOG_SANITIZE_NONE || !needsSanitize(s) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == 0x1b && mode == LOG_SANITIZE_STRIP {
+			i += escapeSequenceLen(s[i:])
+			continue
+		}
+		switch {
+		case c == utf8.RuneError && size == 1:
+			if mode == LOG_SANITIZE_ESCAPE {
+				b.WriteString(`\x` + strconv.FormatUint(uint64(s[i])|0x100, 16)[1:])
+			}
+		case isControl(c):
+			if mode == LOG_SANITIZE_ESCAPE {
+				b.WriteString(escapeControl(c))
+			}
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+func escapeControl(c rune) string {
+	switch c {
+	case '\r':
+		return `\r`
+	case '\b':
+		return `\b`
+	case '\f':
+		return `\f`
+	case '\v':
+		return `\v`
+	case '\a':
+		return `\a`
+	}
+	if c < 0x100 {
+		return `\x` + strconv.FormatUint(uint64(c)|0x100, 16)[1:]
+	}
+	return `\u` + strconv.FormatUint(uint64(c)|0x10000, 16)[1:]
+}
+
+// escapeSequenceLen returns the length of the ANSI escape sequence at the
+// start of s, which begins with ESC: CSI (ESC [ ... final byte), OSC
+// (ESC ] ... BEL or ESC \) or a two byte sequence.
+func escapeSequenceLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
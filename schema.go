@@ -0,0 +1,182 @@
+package elog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaMigration upgrades a decoded JSON record from one schema version to
+// the next, in place.
+type SchemaMigration func(rec map[string]interface{}) error
+
+// schemaMigrations[v] upgrades a record of version v to version v+1,
+// from version 1, the first written by JSONEncoder.
+var schemaMigrations = map[int]SchemaMigration{
+	1: migrateSchemaV1,
+}
+
+var jsonRecordKeys = []string{"schema_version", "time", "level", "logger", "caller", "msg", "fields"}
+
+// MigrateJSON copies JSON log lines from r to w, upgrading every record to
+// LOG_JSON_SCHEMA_VERSION. Lines that are not JSON records of elog, without
+// schema_version, are copied as is.
+func MigrateJSON(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if out, ok := migrateJSONLine(line); ok {
+				writer.Write(out)
+			} else {
+				writer.Write(line)
+			}
+		}
+		if err == io.EOF {
+			return writer.Flush()
+		}
+		if err != nil {
+			writer.Flush()
+			return err
+		}
+	}
+}
+
+func migrateJSONLine(line []byte) ([]byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	var rec map[string]interface{}
+	if err := decoder.Decode(&rec); err != nil || rec == nil {
+		return nil, false
+	}
+	if err := MigrateRecord(rec); err != nil {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for _, k := range jsonRecordKeys {
+		if v, ok := rec[k]; ok {
+			writeJSONKeyValue(&buf, &first, k, v)
+		}
+	}
+	for _, k := range sortedKeys(rec) {
+		if !isJSONRecordKey(k) {
+			writeJSONKeyValue(&buf, &first, k, rec[k])
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes(), true
+}
+
+func writeJSONKeyValue(buf *bytes.Buffer, first *bool, k string, v interface{}) {
+	if !*first {
+		buf.WriteByte(',')
+	}
+	*first = false
+	writeJSONString(buf, k)
+	buf.WriteByte(':')
+	if m, ok := v.(map[string]interface{}); ok {
+		writeJSONObject(buf, m)
+	} else {
+		writeJSONValue(buf, v)
+	}
+}
+
+func isJSONRecordKey(k string) bool {
+	for _, rk := range jsonRecordKeys {
+		if k == rk {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateRecord upgrades a decoded JSON record in place to
+// LOG_JSON_SCHEMA_VERSION. Records without schema_version were not written
+// by JSONEncoder and give an error.
+func MigrateRecord(rec map[string]interface{}) error {
+	v, ok := rec["schema_version"]
+	if !ok {
+		return errors.New("elog: record without schema_version")
+	}
+	version, err := strconv.Atoi(fmt.Sprint(v))
+	if err != nil {
+		return fmt.Errorf("elog: bad schema_version %v", v)
+	}
+	for ; version < LOG_JSON_SCHEMA_VERSION; version++ {
+		migrate, ok := schemaMigrations[version]
+		if !ok {
+			return fmt.Errorf("elog: no migration from schema version %d", version)
+		}
+		if err := migrate(rec); err != nil {
+			return err
+		}
+		rec["schema_version"] = json.Number(strconv.Itoa(version + 1))
+	}
+	return nil
+}
+
+// migrateSchemaV1 turns the "file:line" caller string of version 1 into the
+// caller object of version 2. Function and package are unknown.
+func migrateSchemaV1(rec map[string]interface{}) error {
+	caller, ok := rec["caller"].(string)
+	if !ok {
+		return nil
+	}
+	obj := map[string]interface{}{"file": caller}
+	if colon := strings.LastIndexByte(caller, ':'); colon >= 0 {
+		if line, err := strconv.Atoi(caller[colon+1:]); err == nil {
+			obj["file"] = caller[:colon]
+			obj["line"] = line
+		}
+	}
+	rec["caller"] = obj
+	return nil
+}
+
+// DecodeJSONRecord decodes one line written by JSONEncoder, of any schema
+// version, back into a record. Numbers in fields are json.Number; unknown
+// levels are INFO and a missing time is the zero time.
+func DecodeJSONRecord(line []byte) (*Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	var rec map[string]interface{}
+	if err := decoder.Decode(&rec); err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, errors.New("elog: record is not a JSON object")
+	}
+	if err := MigrateRecord(rec); err != nil {
+		return nil, err
+	}
+	r := &Record{Level: LOG_LEVEL_INFO}
+	if level, ok := rec["level"].(string); ok {
+		r.Level = getLogLevelInt(level)
+	}
+	if s, ok := rec["time"].(string); ok {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		r.Time = t
+	}
+	r.Name, _ = rec["logger"].(string)
+	r.Message, _ = rec["msg"].(string)
+	if caller, ok := rec["caller"].(map[string]interface{}); ok {
+		r.File, _ = caller["file"].(string)
+		r.Line, _ = strconv.Atoi(fmt.Sprint(caller["line"]))
+	}
+	if fields, ok := rec["fields"].(map[string]interface{}); ok && len(fields) > 0 {
+		r.Fields = Fields(fields)
+	}
+	return r, nil
+}
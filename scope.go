@@ -0,0 +1,108 @@
+package elog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+const LOG_SCOPE_MAX_RECORDS = 1000
+
+type scopeKey struct{}
+
+// Scope holds the DEBUG and INFO records logged with its context until the
+// request ends, see BeginScope.
+type Scope struct {
+	mutex   sync.Mutex
+	held    []scopeRecord
+	dropped int
+	failed  bool
+	ended   bool
+}
+
+type scopeRecord struct {
+	logger *EasyLogger
+	r      *Record
+	stack  *Record
+}
+
+// BeginScope returns a copy of ctx whose DEBUG and INFO records, logged with
+// WithContext, are held in memory. They are written when a record of level
+// ERROR or above is logged in the scope, or when End is given an error, and
+// discarded otherwise. WARN and above are always written at once. At most
+// LOG_SCOPE_MAX_RECORDS records are held, the oldest are dropped first.
+func BeginScope(ctx context.Context) (context.Context, *Scope) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Scope{}
+	return context.WithValue(ctx, scopeKey{}, s), s
+}
+
+func scopeFromContext(ctx context.Context) *Scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(scopeKey{}).(*Scope)
+	return s
+}
+
+// hold keeps r back when the scope has not failed yet and reports whether
+// it did. An ERROR record fails the scope and writes the held records first.
+func (s *Scope) hold(el *EasyLogger, r, stack *Record) bool {
+	s.mutex.Lock()
+	if s.ended || s.failed {
+		s.mutex.Unlock()
+		return false
+	}
+	if r.Level < LOG_LEVEL_WARN {
+		if len(s.held) == LOG_SCOPE_MAX_RECORDS {
+			s.held = append(s.held[:0], s.held[1:]...)
+			s.dropped++
+		}
+		s.held = append(s.held, scopeRecord{logger: el, r: r, stack: stack})
+		s.mutex.Unlock()
+		return true
+	}
+	if r.Level < LOG_LEVEL_ERROR {
+		s.mutex.Unlock()
+		return false
+	}
+	s.failed = true
+	held, dropped := s.held, s.dropped
+	s.held = nil
+	s.mutex.Unlock()
+	emitScope(held, dropped)
+	return false
+}
+
+// End ends the scope, writing the held records when err is not nil and
+// discarding them otherwise. Records logged after End are written at once.
+func (s *Scope) End(err error) {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	held, dropped := s.held, s.dropped
+	s.held = nil
+	s.mutex.Unlock()
+	if err != nil {
+		emitScope(held, dropped)
+	}
+}
+
+func emitScope(held []scopeRecord, dropped int) {
+	if dropped > 0 {
+		selfLogf(LOG_LEVEL_WARN, "scope: %d records dropped", dropped)
+	}
+	for _, sr := range held {
+		el := sr.logger
+		el.lock()
+		if atomic.LoadInt32(&el.closed) == 0 {
+			el.commit(sr.r, sr.stack)
+		}
+		el.unlock()
+	}
+}
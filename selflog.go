@@ -0,0 +1,79 @@
+package elog
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LOG_SELF_NAME = "elog"
+)
+
+// The self log carries elog's own diagnostics: rotations, reconnects,
+// dropped records and handler errors. They go to stderr from WARN up unless
+// SetSelfLogger and SetSelfLogLevel say otherwise.
+type selfLog struct {
+	logger *EasyLogger
+	level  int
+}
+
+var selfLogMutex sync.Mutex
+var selfLogValue atomic.Value
+
+func init() {
+	selfLogValue.Store(&selfLog{level: LOG_LEVEL_WARN})
+}
+
+func updateSelfLog(fn func(sl *selfLog)) {
+	selfLogMutex.Lock()
+	defer selfLogMutex.Unlock()
+	sl := *selfLogValue.Load().(*selfLog)
+	fn(&sl)
+	selfLogValue.Store(&sl)
+}
+
+// SetSelfLogger routes elog's diagnostics to el, records are named "elog".
+// nil restores stderr.
+func SetSelfLogger(el *EasyLogger) {
+	updateSelfLog(func(sl *selfLog) {
+		sl.logger = el
+	})
+}
+
+// SetSelfLogLevel sets the lowest level of the diagnostics, "NONE" silences
+// them.
+func SetSelfLogLevel(level string) {
+	updateSelfLog(func(sl *selfLog) {
+		sl.level = getLogLevelInt(level)
+	})
+}
+
+// selfLogf reports an event of elog itself. Callers must not hold a handler
+// mutex: the self logger may write to that very handler.
+func selfLogf(level int, format string, args ...interface{}) {
+	sl := selfLogValue.Load().(*selfLog)
+	if level < sl.level {
+		return
+	}
+	file, line, pc := getCaller(2)
+	selfLogRecord(&Record{Level: level, File: file, Line: line, PC: pc, Message: fmt.Sprintf(format, args...)})
+}
+
+// selfLogRecord reports an event of elog itself carrying fields.
+func selfLogRecord(r *Record) {
+	sl := selfLogValue.Load().(*selfLog)
+	if r.Level < sl.level {
+		return
+	}
+	r.Name = LOG_SELF_NAME
+	r.Time = time.Now()
+	if sl.logger == nil {
+		TextEncoder{}.Encode(os.Stderr, r)
+		return
+	}
+	r.Time = sl.logger.clock.Now()
+	sl.logger.record(r)
+}
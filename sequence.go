@@ -0,0 +1,19 @@
+package elog
+
+import "sync/atomic"
+
+const LOG_FIELD_SEQ = "seq"
+
+// WithSequence numbers the records of the logger 1, 2, 3... in the seq
+// field, in the order they are written, so consumers of shipped logs can
+// detect dropped or reordered records.
+func WithSequence() Option {
+	return func(el *EasyLogger) {
+		el.sequence = true
+	}
+}
+
+// Sequence returns the number of the last record written with WithSequence.
+func (el *EasyLogger) Sequence() uint64 {
+	return atomic.LoadUint64(&el.seq)
+}
@@ -0,0 +1,119 @@
+package elog
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	LOG_SPILL_QUEUE_SIZE = 10000
+)
+
+// ErrRecordDeadline is returned by a DeadlineWriter for a record it could
+// not hand over before the deadline of the record's context.
+var ErrRecordDeadline = errors.New("elog: record deadline exceeded")
+
+// DeadlineWriter is implemented by remote handlers that can bound a write
+// by the deadline of the record's context, so a request about to time out
+// does not wait on a slow log shipment. The logger calls WriteRecordBefore
+// instead of WriteRecord for records logged with such a context, see
+// WithContext; it returns ErrRecordDeadline for the records it gave up on.
+type DeadlineWriter interface {
+	WriteRecordBefore(r *Record, p []byte, deadline time.Time) (int, error)
+}
+
+type spilledRecord struct {
+	handler io.Writer
+	r       *Record
+	data    []byte
+}
+
+type spillQueue struct {
+	size     int
+	records  []spilledRecord
+	spilled  int64
+	replayed int64
+	dropped  int64
+}
+
+// SpillQueueStats reports the spill queue: Pending records wait for their
+// handler, Spilled missed their deadline, Replayed reached their handler
+// later and Dropped were discarded for a full queue.
+type SpillQueueStats struct {
+	Pending  int
+	Spilled  int64
+	Replayed int64
+	Dropped  int64
+}
+
+// WithSpillQueue keeps up to size records (LOG_SPILL_QUEUE_SIZE when 0)
+// that missed their context deadline in memory and writes them when the
+// handlers are flushed, without the deadline. Without it such records are
+// dropped and counted as handler errors.
+func WithSpillQueue(size int) Option {
+	return func(el *EasyLogger) {
+		if size <= 0 {
+			size = LOG_SPILL_QUEUE_SIZE
+		}
+		if el.spill == nil {
+			el.spill = &spillQueue{}
+		}
+		el.spill.size = size
+	}
+}
+
+// writeEncoded hands an encoded record to w in one write, bounded by the
+// deadline of the record's context when w is a DeadlineWriter.
+func (el *EasyLogger) writeEncoded(w io.Writer, r *Record, data []byte) (int, error) {
+	if dw, ok := w.(DeadlineWriter); ok && r.Context != nil {
+		if deadline, ok := r.Context.Deadline(); ok {
+			n, err := dw.WriteRecordBefore(r, data, deadline)
+			if err == ErrRecordDeadline && el.spill != nil {
+				el.spill.add(w, r, data)
+			}
+			return n, err
+		}
+	}
+	return writeEncoded(w, r, data)
+}
+
+// add keeps a copy of data, the encoded record being pooled. It is called
+// with the mutex held.
+func (sq *spillQueue) add(w io.Writer, r *Record, data []byte) {
+	if len(sq.records) >= sq.size {
+		sq.dropped++
+		return
+	}
+	sq.spilled++
+	sq.records = append(sq.records, spilledRecord{handler: w, r: r, data: append([]byte(nil), data...)})
+}
+
+// replay writes the spilled records in order, stopping at the first
+// failure to retry it at the next flush. It is called with the mutex held.
+func (sq *spillQueue) replay() {
+	for len(sq.records) > 0 {
+		s := sq.records[0]
+		if _, err := writeEncoded(s.handler, s.r, s.data); err != nil {
+			return
+		}
+		sq.records[0] = spilledRecord{}
+		sq.records = sq.records[1:]
+		sq.replayed++
+	}
+	sq.records = nil
+}
+
+func (el *EasyLogger) SpillStats() SpillQueueStats {
+	el.lock()
+	defer el.unlock()
+	if el.spill == nil {
+		return SpillQueueStats{}
+	}
+	sq := el.spill
+	return SpillQueueStats{Pending: len(sq.records), Spilled: sq.spilled, Replayed: sq.replayed, Dropped: sq.dropped}
+}
+
+func SpillStats() SpillQueueStats {
+	return logger.SpillStats()
+}
@@ -0,0 +1,55 @@
+package elog
+
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// DumpGoroutinesOn makes every record at level or above be followed by a
+// record holding the stack traces of all goroutines. A level of 0 disables
+// the dump.
+func (el *EasyLogger) DumpGoroutinesOn(level int) {
+	atomic.StoreInt32(&el.dumpLevel, int32(level))
+}
+
+func DumpGoroutinesOn(level int) {
+	logger.DumpGoroutinesOn(level)
+}
+
+// DumpAllGoroutines writes the stack traces of all goroutines as an ERROR
+// record, regardless of the configured log level.
+func (el *EasyLogger) DumpAllGoroutines() {
+	el.dumpAllGoroutines()
+}
+
+func DumpAllGoroutines() {
+	logger.DumpAllGoroutines()
+}
+
+func (el *EasyLogger) dumpAllGoroutines() {
+	if atomic.LoadInt32(&el.closed) != 0 {
+		return
+	}
+	file, line, pc := getCaller(el.depth)
+	el.record(&Record{Level: LOG_LEVEL_ERROR, Time: el.clock.Now(), File: file, Line: line, PC: pc, Message: "goroutine dump\n" + allGoroutineStacks()})
+}
+
+func (el *EasyLogger) stackRecord(r *Record) *Record {
+	level := int(atomic.LoadInt32(&el.dumpLevel))
+	if level == 0 || r.Level < level {
+		return nil
+	}
+	return &Record{Level: r.Level, Name: r.Name, Time: r.Time, File: r.File, Line: r.Line, PC: r.PC, Message: "goroutine dump\n" + allGoroutineStacks()}
+}
+
+func allGoroutineStacks() string {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return strings.TrimRight(string(buf[:n]), "\n")
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9 || js || wasip1
+// +build windows plan9 js wasip1
+
+package elog
+
+import "net"
+
+// peerClosed cannot peek at a connection here; a closed peer shows when a
+// write fails.
+func peerClosed(conn net.Conn) error {
+	return nil
+}
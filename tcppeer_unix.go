@@ -0,0 +1,37 @@
+//go:build !windows && !plan9 && !js && !wasip1
+// +build !windows,!plan9,!js,!wasip1
+
+package elog
+
+import (
+	"io"
+	"net"
+	"syscall"
+)
+
+// peerClosed peeks at conn, non-blocking like every Go socket, and
+// returns the error of a peer that closed or reset it. Log destinations
+// send nothing, so any data pending means the peer is alive.
+func peerClosed(conn net.Conn) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var closed error
+	var buf [1]byte
+	rc.Read(func(fd uintptr) bool {
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK)
+		switch {
+		case n == 0 && err == nil:
+			closed = io.EOF
+		case err != nil && err != syscall.EAGAIN && err != syscall.EWOULDBLOCK && err != syscall.EINTR:
+			closed = err
+		}
+		return true
+	})
+	return closed
+}
@@ -0,0 +1,41 @@
+package elog
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// NewEasyTempFileHandler creates a file handler writing to a new directory
+// under the OS temp dir, named after prefix, for tests and short-lived
+// commands. Close removes the directory with the logs unless
+// SetKeepTempDir(true) was called, e.g. to inspect a failed test.
+func NewEasyTempFileHandler(prefix string, bufferSize int) (*EasyFileHandler, error) {
+	dir, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		return nil, err
+	}
+	handler := NewEasyFileHandler(dir, bufferSize)
+	handler.tempDir = dir
+	return handler, nil
+}
+
+func (efh *EasyFileHandler) SetKeepTempDir(keep bool) {
+	efh.keepTempDir = keep
+}
+
+// Dir returns the directory the handler writes to.
+func (efh *EasyFileHandler) Dir() string {
+	return efh.path
+}
+
+// removeTempDir runs on Close.
+func (efh *EasyFileHandler) removeTempDir() error {
+	if efh.tempDir == "" || efh.keepTempDir {
+		return nil
+	}
+	dir := efh.tempDir
+	efh.tempDir = ""
+	return retryFileOp(func() error {
+		return os.RemoveAll(dir)
+	})
+}
@@ -0,0 +1,128 @@
+package elog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying the tenant ID used by
+// WithTenancy.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantHandlerFactory creates the handler receiving the records of one
+// tenant.
+type TenantHandlerFactory func(tenant string) (io.Writer, error)
+
+// WithTenancy routes every record carrying a tenant ID, taken from the
+// record context (ContextWithTenant) or else from the field named key, to a
+// handler of its own created on first use by factory. Each tenant handler
+// rotates independently. Records without a tenant go to the logger's
+// handler; records whose tenant handler cannot be created are dropped,
+// never written to another tenant's handler.
+func WithTenancy(key string, factory TenantHandlerFactory) Option {
+	return func(el *EasyLogger) {
+		el.tenancy = &tenancy{key: key, factory: factory, handlers: map[string]*tenantHandler{}}
+	}
+}
+
+// NewTenantFileHandlerFactory returns a factory logging each tenant into its
+// own EasyFileHandler under path/<tenant>.
+func NewTenantFileHandlerFactory(path string, bufferSize int) TenantHandlerFactory {
+	return func(tenant string) (io.Writer, error) {
+		if err := checkTenantID(tenant); err != nil {
+			return nil, err
+		}
+		dir := filepath.Join(path, tenant)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		return NewEasyFileHandler(dir, bufferSize), nil
+	}
+}
+
+func checkTenantID(tenant string) error {
+	if tenant == "" || tenant == "." || tenant == ".." || strings.ContainsAny(tenant, "/\\\x00") {
+		return errors.New("elog: invalid tenant id " + fmt.Sprintf("%q", tenant))
+	}
+	return nil
+}
+
+type tenancy struct {
+	key      string
+	factory  TenantHandlerFactory
+	handlers map[string]*tenantHandler
+}
+
+type tenantHandler struct {
+	writer io.Writer
+	stat   handlerStat
+}
+
+func (t *tenancy) tenant(r *Record) (string, bool) {
+	if tenant, ok := TenantFromContext(r.Context); ok {
+		return tenant, true
+	}
+	if v, ok := r.Fields[t.key]; ok {
+		tenant := fmt.Sprint(v)
+		return tenant, tenant != ""
+	}
+	return "", false
+}
+
+// handler returns the handler of the tenant of r and its statistics, def
+// when r has no tenant, or nil when the tenant handler cannot be created.
+func (t *tenancy) handler(r *Record, def io.Writer, defStat *handlerStat) (io.Writer, *handlerStat) {
+	tenant, ok := t.tenant(r)
+	if !ok {
+		return def, defStat
+	}
+	if th, ok := t.handlers[tenant]; ok {
+		return th.writer, &th.stat
+	}
+	h, err := t.factory(tenant)
+	if err != nil {
+		selfLogf(LOG_LEVEL_ERROR, "tenant %s: %v", tenant, err)
+		return nil, nil
+	}
+	th := &tenantHandler{writer: h}
+	t.handlers[tenant] = th
+	return th.writer, &th.stat
+}
+
+func (t *tenancy) flush(el *EasyLogger) error {
+	var err error
+	for _, th := range t.handlers {
+		if ferr := th.stat.flush(el, th.writer); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+func (t *tenancy) close() error {
+	var err error
+	for _, th := range t.handlers {
+		flushHandler(th.writer)
+		if cerr := closeHandler(th.writer); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,304 @@
+// Package tiny is a reduced elog for TinyGo and microcontrollers: no flags,
+// no files, no rotation and no goroutines. Records are written
+// synchronously, in the elog text format, to an io.Writer such as a UART.
+// Importing it as elog keeps the Info/Error call sites of the full package:
+//
+//	import elog "github.com/starjiang/elog/tiny"
+package tiny
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// the values of the full package
+	LOG_LEVEL_DEBUG = 10
+	LOG_LEVEL_INFO  = 20
+	LOG_LEVEL_WARN  = 30
+	LOG_LEVEL_ERROR = 40
+	LOG_LEVEL_FATAL = 50
+	LOG_LEVEL_NONE  = 60
+
+	LOG_EXIT_CODE_FATAL = 1
+
+	// output is called by log or logf, called by the Info-like functions
+	// and methods alike.
+	LOG_DEPTH_CALLER = 3
+)
+
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL", "NONE"}
+
+// Logger writes records of level and above to its writer.
+type Logger struct {
+	mutex  sync.Mutex
+	level  int32
+	writer io.Writer
+	caller bool
+	clock  func() time.Time
+	buf    []byte
+}
+
+// New returns a logger writing to w. Timestamps and callers are off: most
+// boards have no wall clock and runtime.Caller costs flash.
+func New(level string, w io.Writer) *Logger {
+	return &Logger{level: levelValue(level), writer: w}
+}
+
+var logger = &Logger{level: LOG_LEVEL_INFO, writer: os.Stdout}
+
+// SetOutput replaces the writer of the global logger, stdout by default.
+func SetOutput(w io.Writer) {
+	logger.mutex.Lock()
+	logger.writer = w
+	logger.mutex.Unlock()
+}
+
+func (l *Logger) SetLevel(level string) {
+	atomic.StoreInt32(&l.level, levelValue(level))
+}
+
+func SetLevel(level string) {
+	logger.SetLevel(level)
+}
+
+// SetCaller adds [file:f line:n] to the records.
+func (l *Logger) SetCaller(on bool) {
+	l.mutex.Lock()
+	l.caller = on
+	l.mutex.Unlock()
+}
+
+func SetCaller(on bool) {
+	logger.SetCaller(on)
+}
+
+// SetClock stamps records with the time returned by now, e.g. an RTC
+// reading; nil leaves the time out.
+func (l *Logger) SetClock(now func() time.Time) {
+	l.mutex.Lock()
+	l.clock = now
+	l.mutex.Unlock()
+}
+
+func SetClock(now func() time.Time) {
+	logger.SetClock(now)
+}
+
+func levelValue(level string) int32 {
+	for i, name := range levelNames {
+		if name == level {
+			return int32(i+1) * 10
+		}
+	}
+	return LOG_LEVEL_INFO
+}
+
+func (l *Logger) enabled(level int) bool {
+	return int32(level) >= atomic.LoadInt32(&l.level)
+}
+
+func (l *Logger) output(level int, msg string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	b := append(l.buf[:0], '[')
+	b = append(b, levelNames[level/10-1]...)
+	b = append(b, ']')
+	if l.clock != nil {
+		b = append(b, '[')
+		b = l.clock().AppendFormat(b, "2006-01-02 15:04:05")
+		b = append(b, ']')
+	}
+	if l.caller {
+		_, file, line, ok := runtime.Caller(LOG_DEPTH_CALLER)
+		if !ok {
+			file, line = "???", 1
+		}
+		if slash := strings.LastIndex(file, "/"); slash >= 0 {
+			file = file[slash+1:]
+		}
+		b = append(b, "[file:"...)
+		b = append(b, file...)
+		b = append(b, " line:"...)
+		b = strconv.AppendInt(b, int64(line), 10)
+		b = append(b, ']')
+	}
+	b = append(b, ' ')
+	b = append(b, msg...)
+	b = append(b, '\n')
+	l.buf = b
+	l.writer.Write(b)
+}
+
+func (l *Logger) log(level int, args ...interface{}) {
+	if l.enabled(level) {
+		l.output(level, sprintln(args...))
+	}
+}
+
+func (l *Logger) logf(level int, format string, args ...interface{}) {
+	if l.enabled(level) {
+		l.output(level, fmt.Sprintf(format, args...))
+	}
+}
+
+func sprintln(args ...interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.log(LOG_LEVEL_DEBUG, args...)
+}
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_DEBUG, format, args...)
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.log(LOG_LEVEL_INFO, args...)
+}
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_INFO, format, args...)
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.log(LOG_LEVEL_WARN, args...)
+}
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_WARN, format, args...)
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.log(LOG_LEVEL_ERROR, args...)
+}
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_ERROR, format, args...)
+}
+
+// Fatal logs and stops the program with os.Exit(1).
+func (l *Logger) Fatal(args ...interface{}) {
+	l.log(LOG_LEVEL_FATAL, args...)
+	os.Exit(LOG_EXIT_CODE_FATAL)
+}
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_FATAL, format, args...)
+	os.Exit(LOG_EXIT_CODE_FATAL)
+}
+
+func (l *Logger) FatalCode(code int, args ...interface{}) {
+	l.log(LOG_LEVEL_FATAL, args...)
+	os.Exit(code)
+}
+func (l *Logger) FatalCodef(code int, format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_FATAL, format, args...)
+	os.Exit(code)
+}
+
+// Panic logs at FATAL level and panics with the message.
+func (l *Logger) Panic(args ...interface{}) {
+	l.log(LOG_LEVEL_FATAL, args...)
+	panic(sprintln(args...))
+}
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_FATAL, format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
+
+// Log logs at the level named levelName; unknown names log at INFO.
+func (l *Logger) Log(levelName string, args ...interface{}) {
+	l.log(int(levelValue(levelName)), args...)
+}
+func (l *Logger) Logf(levelName string, format string, args ...interface{}) {
+	l.logf(int(levelValue(levelName)), format, args...)
+}
+
+func (l *Logger) Println(args ...interface{}) {
+	l.log(LOG_LEVEL_INFO, args...)
+}
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.logf(LOG_LEVEL_INFO, format, args...)
+}
+
+func Debug(args ...interface{}) {
+	logger.log(LOG_LEVEL_DEBUG, args...)
+}
+func Debugf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_DEBUG, format, args...)
+}
+
+func Info(args ...interface{}) {
+	logger.log(LOG_LEVEL_INFO, args...)
+}
+func Infof(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_INFO, format, args...)
+}
+
+func Warn(args ...interface{}) {
+	logger.log(LOG_LEVEL_WARN, args...)
+}
+func Warnf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_WARN, format, args...)
+}
+func Warningf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_WARN, format, args...)
+}
+
+func Error(args ...interface{}) {
+	logger.log(LOG_LEVEL_ERROR, args...)
+}
+func Errorf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_ERROR, format, args...)
+}
+
+func Fatal(args ...interface{}) {
+	logger.log(LOG_LEVEL_FATAL, args...)
+	os.Exit(LOG_EXIT_CODE_FATAL)
+}
+func Fatalf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_FATAL, format, args...)
+	os.Exit(LOG_EXIT_CODE_FATAL)
+}
+
+func FatalCode(code int, args ...interface{}) {
+	logger.log(LOG_LEVEL_FATAL, args...)
+	os.Exit(code)
+}
+func FatalCodef(code int, format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_FATAL, format, args...)
+	os.Exit(code)
+}
+
+func Panic(args ...interface{}) {
+	logger.log(LOG_LEVEL_FATAL, args...)
+	panic(sprintln(args...))
+}
+func Panicf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_FATAL, format, args...)
+	panic(fmt.Sprintf(format, args...))
+}
+
+func Log(levelName string, args ...interface{}) {
+	logger.log(int(levelValue(levelName)), args...)
+}
+func Logf(levelName string, format string, args ...interface{}) {
+	logger.logf(int(levelValue(levelName)), format, args...)
+}
+
+func Println(args ...interface{}) {
+	logger.log(LOG_LEVEL_INFO, args...)
+}
+func Printf(format string, args ...interface{}) {
+	logger.logf(LOG_LEVEL_INFO, format, args...)
+}
+
+// Flush is a no-op kept for call sites shared with the full package;
+// records are never buffered.
+func Flush() {
+}
@@ -0,0 +1,29 @@
+package tiny
+
+import "io"
+
+// EasyUARTHandler writes to a serial port, e.g. machine.UART0 under TinyGo,
+// ending lines with \r\n as serial terminals expect.
+type EasyUARTHandler struct {
+	port io.Writer
+	buf  []byte
+}
+
+func NewEasyUARTHandler(port io.Writer) *EasyUARTHandler {
+	return &EasyUARTHandler{port: port}
+}
+
+func (euh *EasyUARTHandler) Write(data []byte) (int, error) {
+	b := euh.buf[:0]
+	for _, c := range data {
+		if c == '\n' {
+			b = append(b, '\r')
+		}
+		b = append(b, c)
+	}
+	euh.buf = b
+	if _, err := euh.port.Write(b); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
@@ -0,0 +1,158 @@
+package elog
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TransportConfig secures the connections of the remote handlers the same
+// way for all of them.
+type TransportConfig struct {
+	// TLS turns TLS on; it is implied by any of the certificate settings.
+	TLS bool
+	// CAFile is a PEM bundle of the trusted CAs, the system roots if empty.
+	CAFile string
+	// CertFile and KeyFile hold the client certificate for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified against the server
+	// certificate, the host of the address by default.
+	ServerName string
+	// PinnedSHA256 lists hex SHA-256 hashes of public keys (SubjectPublicKeyInfo);
+	// when set, the verified chain must contain one of them.
+	PinnedSHA256 []string
+	// BearerToken or Username and Password authenticate to the server,
+	// where the protocol allows it.
+	BearerToken string
+	Username    string
+	Password    string
+	// Dialer opens the connections instead of net.Dialer, e.g. through a
+	// SOCKS proxy, with custom name resolution or to a unix socket.
+	Dialer Dialer
+	// RoundTripper sends the requests of HTTP handlers, e.g. one with an
+	// HTTP proxy. TLS settings above do not apply to it.
+	RoundTripper http.RoundTripper
+}
+
+// Dialer is implemented by *net.Dialer and by proxy dialers.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+func (tc TransportConfig) dial(address string, timeout time.Duration) (net.Conn, error) {
+	if tc.Dialer == nil {
+		return net.DialTimeout("tcp", address, timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return tc.Dialer.DialContext(ctx, "tcp", address)
+}
+
+// roundTripper returns the RoundTripper of HTTP handlers connecting to
+// host.
+func (tc TransportConfig) roundTripper(host string) (http.RoundTripper, error) {
+	if tc.RoundTripper != nil {
+		return tc.RoundTripper, nil
+	}
+	tlsConfig, err := tc.tlsConfig(host)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	if tc.Dialer != nil {
+		transport.DialContext = tc.Dialer.DialContext
+	}
+	return transport, nil
+}
+
+func (tc TransportConfig) tlsEnabled() bool {
+	return tc.TLS || tc.CAFile != "" || tc.CertFile != "" || len(tc.PinnedSHA256) > 0
+}
+
+// tlsConfig returns the client TLS configuration for host, nil without TLS.
+func (tc TransportConfig) tlsConfig(host string) (*tls.Config, error) {
+	if !tc.tlsEnabled() {
+		return nil, nil
+	}
+	config := &tls.Config{ServerName: tc.ServerName, MinVersion: tls.VersionTLS12}
+	if config.ServerName == "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		config.ServerName = host
+	}
+	if tc.CAFile != "" {
+		pem, err := ioutil.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("elog: no certificates in " + tc.CAFile)
+		}
+	}
+	if tc.CertFile != "" || tc.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(tc.PinnedSHA256) > 0 {
+		pins := map[string]bool{}
+		for _, pin := range tc.PinnedSHA256 {
+			pins[strings.ToLower(strings.Replace(pin, ":", "", -1))] = true
+		}
+		config.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if pins[hex.EncodeToString(sum[:])] {
+						return nil
+					}
+				}
+			}
+			return errors.New("elog: no pinned public key in the server certificate chain")
+		}
+	}
+	return config, nil
+}
+
+// dialTLS wraps conn in TLS when the transport asks for it, the handshake
+// must finish within timeout. conn is closed on failure.
+func (tc TransportConfig) dialTLS(conn net.Conn, host string, timeout time.Duration) (net.Conn, error) {
+	config, err := tc.tlsConfig(host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if config == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, config)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// authorize sets the Authorization header of req.
+func (tc TransportConfig) authorize(req *http.Request) {
+	if tc.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+tc.BearerToken)
+	} else if tc.Username != "" {
+		req.SetBasicAuth(tc.Username, tc.Password)
+	}
+}
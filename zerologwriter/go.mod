@@ -0,0 +1,10 @@
+module github.com/starjiang/elog/zerologwriter
+
+go 1.15
+
+require (
+	github.com/rs/zerolog v1.33.0
+	github.com/starjiang/elog v0.0.0
+)
+
+replace github.com/starjiang/elog => ../
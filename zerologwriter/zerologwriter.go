@@ -0,0 +1,55 @@
+// Package zerologwriter is a zerolog.LevelWriter turning zerolog events
+// into elog records, with their level, time, caller and fields, so both
+// libraries share the elog handlers while a project migrates:
+//
+//	zl := zerolog.New(zerologwriter.New(log)).With().Timestamp().Logger()
+//
+// It is a module of its own so that elog does not depend on zerolog.
+package zerologwriter
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/starjiang/elog"
+)
+
+// Writer is a zerolog.LevelWriter writing every event to an elog logger.
+type Writer struct {
+	el  *elog.EasyLogger
+	ebw *elog.EasyBridgeWriter
+}
+
+func New(el *elog.EasyLogger) *Writer {
+	return &Writer{el: el, ebw: elog.NewZerologWriter(el)}
+}
+
+// Write takes the level of the event from its level field, INFO without.
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.ebw.Write(p)
+}
+
+// WriteLevel writes the event at level. The logger is flushed for fatal and
+// panic events, as zerolog exits or panics right after writing them.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	elevel := Level(level)
+	n, err := w.ebw.WriteLevel(elevel, p)
+	if err == nil && elevel >= elog.LOG_LEVEL_FATAL {
+		err = w.el.Flush()
+	}
+	return n, err
+}
+
+// Level maps a zerolog level to the elog one: trace to DEBUG, panic to
+// FATAL and events without a level to INFO.
+func Level(level zerolog.Level) int {
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return elog.LOG_LEVEL_DEBUG
+	case zerolog.WarnLevel:
+		return elog.LOG_LEVEL_WARN
+	case zerolog.ErrorLevel:
+		return elog.LOG_LEVEL_ERROR
+	case zerolog.FatalLevel, zerolog.PanicLevel:
+		return elog.LOG_LEVEL_FATAL
+	}
+	return elog.LOG_LEVEL_INFO
+}